@@ -35,6 +35,10 @@ type ChartSpec struct {
 	Versions []string `json:"versions,omitempty"`
 	// The tags to check to enable chart
 	CreateDeps bool `json:"createDeps,omitempty"`
+	// Verify enables provenance verification for this chart and takes precedence over the repository
+	Verify *ChartVerification `json:"verify,omitempty"`
+	// Suspend stops syncing the versions of the chart until it is unset
+	Suspend bool `json:"suspend,omitempty"`
 }
 
 // ChartDep represents data for parsing a chart dependency
@@ -64,6 +68,8 @@ type ChartStatus struct {
 	Deprecated   *bool              `json:"deprecated,omitempty"`
 	Type         *string            `json:"type,omitempty"`
 	Tags         *string            `json:"tags,omitempty"`
+	// ResolvedVersions maps the requested versions or constraints to chart versions
+	ResolvedVersions map[string]string `json:"resolvedVersions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -33,17 +33,45 @@ type ConfigSpec struct {
 	Flags              *Flags    `json:"flags,omitempty"`
 	Namespace          Namespace `json:"namespace,omitempty"`
 	ServiceAccountName string    `json:"serviceAccountName"`
+	// Credentials defines how releases act as the service account. 'secret' reads the token of a service account token secret,
+	// 'impersonate' uses the credentials of the agent impersonating the service account
+	// and 'tokenRequest' requests short-lived tokens. Default is secret
+	// +kubebuilder:validation:Enum=secret;impersonate;tokenRequest
+	Credentials string `json:"credentials,omitempty"`
+	// Cluster is used as target of releases without an own cluster reference
+	Cluster *ClusterReference `json:"cluster,omitempty"`
+	// PostRenderer is used for releases without an own post renderer
+	PostRenderer *PostRenderer `json:"postRenderer,omitempty"`
+	// Uninstall is used for releases without an own uninstall policy
+	Uninstall *UninstallPolicy `json:"uninstall,omitempty"`
+}
+
+// ClusterReference points to a secret with a kubeconfig of a remote cluster
+type ClusterReference struct {
+	// Name identifies the cluster in the release status
+	Name string `json:"name"`
+	// KubeconfigSecret is the name of the secret in the namespace of the release
+	KubeconfigSecret string `json:"kubeconfigSecret"`
+	// Key of the kubeconfig in the secret. Default is kubeconfig
+	Key string `json:"key,omitempty"`
+	// Context of the kubeconfig which should be used instead of the current context
+	Context string `json:"context,omitempty"`
 }
 
 // Namespace represents struct for release namespace data
 type Namespace struct {
 	Allowed []string `json:"allowed,omitempty"`
-	Install bool     `json:"install,omitempty"`
+	// Install creates missing release namespaces
+	Install bool `json:"install,omitempty"`
+	// Labels and Annotations are set on created namespaces
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
 }
 
 type Sync struct {
-	Enabled  bool `json:"enabled,omitempty"`
-	Interval int  `json:"interval,omitempty"`
+	Enabled bool `json:"enabled,omitempty"`
+	// Interval in seconds between two syncs, defaults to 10 seconds
+	Interval int `json:"interval,omitempty"`
 }
 
 // Flags represents data for parsing flags for creating release resources
@@ -60,6 +88,8 @@ type Flags struct {
 	Description              string        `json:"description,omitempty"`
 	Recreate                 bool          `json:"recreate,omitempty"`
 	CleanupOnFail            bool          `json:"cleanupOnFail,omitempty"`
+	// Remediation is used for releases without an own remediation policy
+	Remediation *RemediationPolicy `json:"remediation,omitempty"`
 }
 
 // ConfigStatus defines the observed state of Config
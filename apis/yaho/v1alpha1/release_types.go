@@ -36,8 +36,269 @@ type ReleaseSpec struct {
 	Version   string   `json:"version,omitempty"`
 	Config    *string  `json:"config,omitempty"`
 	Values    []string `json:"values,omitempty"`
+	// Upgrade enables automatic upgrades to newer versions matching the version constraint
+	Upgrade *UpgradePolicy `json:"upgrade,omitempty"`
+	// Drift enables periodic comparison of the live objects with the manifest of the deployed revision
+	Drift *DriftDetection `json:"drift,omitempty"`
+	// RollbackTo rolls the release back to the given helm revision. The release returns to the spec after removing it
+	RollbackTo *int `json:"rollbackTo,omitempty"`
+	// Remediation defines how failed installs and upgrades are handled and takes precedence over the config
+	Remediation *RemediationPolicy `json:"remediation,omitempty"`
+	// Plan stores the diff of pending changes in a configmap and applies them only after approval
+	Plan bool `json:"plan,omitempty"`
+	// Cluster references a remote cluster the release is deployed to and takes precedence over the config
+	Cluster *ClusterReference `json:"cluster,omitempty"`
+	// Tests defines when the test hooks of the chart are executed
+	Tests *ReleaseTests `json:"tests,omitempty"`
+	// PostRenderer modifies the rendered manifests and takes precedence over the config
+	PostRenderer *PostRenderer `json:"postRenderer,omitempty"`
+	// DependsOn lists releases which have to be synced and ready before the release is installed or upgraded.
+	// Releases are uninstalled only after all releases depending on them are deleted
+	DependsOn []ReleaseDependency `json:"dependsOn,omitempty"`
+	// Health defines custom health rules and how often unhealthy releases are assessed again
+	Health *HealthAssessment `json:"health,omitempty"`
+	// Uninstall defines what happens with the helm release on deletion and takes precedence over the config
+	Uninstall *UninstallPolicy `json:"uninstall,omitempty"`
+	// Suspend stops installs, upgrades and rollbacks of the release until it is unset. Deletions are still handled
+	Suspend bool `json:"suspend,omitempty"`
 }
 
+// UninstallPolicy defines how the helm release is removed when the release resource is deleted
+type UninstallPolicy struct {
+	// Policy 'delete' uninstalls the release, 'keepHistory' uninstalls it but keeps its history
+	// and 'orphan' leaves the helm release and its resources untouched. Default is delete
+	// +kubebuilder:validation:Enum=delete;keepHistory;orphan
+	Policy string `json:"policy,omitempty"`
+	// Wait waits until all resources of the release are deleted
+	Wait bool `json:"wait,omitempty"`
+	// Timeout in seconds for hooks and waiting. Default is 300
+	Timeout int64 `json:"timeout,omitempty"`
+	// DisableHooks skips the delete hooks of the chart
+	DisableHooks bool `json:"disableHooks,omitempty"`
+	// DeleteCRDs deletes the CRDs of the chart which helm never deletes. All custom resources of these CRDs are lost
+	DeleteCRDs bool `json:"deleteCRDs,omitempty"`
+	// GiveUpAfter is the time in seconds after the deletion after which a failing uninstall is given up
+	// and the release resource is removed anyway. Default is 600
+	GiveUpAfter int64 `json:"giveUpAfter,omitempty"`
+}
+
+// HealthAssessment defines the assessment of the resources of the deployed revision
+type HealthAssessment struct {
+	// Interval in seconds between two assessments while the release is unhealthy. Default is 30
+	Interval int64 `json:"interval,omitempty"`
+	// Rules define the health of custom resources and take precedence over the builtin checks
+	Rules []HealthRule `json:"rules,omitempty"`
+}
+
+// HealthRule defines when resources of a kind are healthy
+type HealthRule struct {
+	Group string `json:"group,omitempty"`
+	Kind  string `json:"kind"`
+	// Condition is the type of a status condition which has to be true. Default is Ready if no field is set
+	Condition string `json:"condition,omitempty"`
+	// Field is a dot separated path like status.phase whose value has to be one of the values
+	Field  string   `json:"field,omitempty"`
+	Values []string `json:"values,omitempty"`
+}
+
+// ResourceHealth represents the health of a resource of the deployed revision
+type ResourceHealth struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Healthy   bool   `json:"healthy"`
+	Message   string `json:"message,omitempty"`
+}
+
+// ReleaseDependency references another release resource
+type ReleaseDependency struct {
+	Name string `json:"name"`
+	// Namespace of the release resource. Default is the namespace of the depending release
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// PostRenderer defines patches and common metadata which are applied to the rendered manifests before they are deployed
+type PostRenderer struct {
+	// Patches are strategic merge patches or JSON6902 patches
+	Patches []PostRendererPatch `json:"patches,omitempty"`
+	// CommonLabels are added to all objects and selectors
+	CommonLabels map[string]string `json:"commonLabels,omitempty"`
+	// CommonAnnotations are added to all objects
+	CommonAnnotations map[string]string `json:"commonAnnotations,omitempty"`
+}
+
+// PostRendererPatch represents a single patch and the objects it is applied to
+type PostRendererPatch struct {
+	// Patch is a strategic merge patch or a JSON6902 patch in yaml
+	Patch string `json:"patch"`
+	// Target selects the patched objects. It is required for JSON6902 patches
+	Target *PatchTarget `json:"target,omitempty"`
+}
+
+// PatchTarget selects objects of the rendered manifests
+type PatchTarget struct {
+	Group              string `json:"group,omitempty"`
+	Version            string `json:"version,omitempty"`
+	Kind               string `json:"kind,omitempty"`
+	Name               string `json:"name,omitempty"`
+	Namespace          string `json:"namespace,omitempty"`
+	LabelSelector      string `json:"labelSelector,omitempty"`
+	AnnotationSelector string `json:"annotationSelector,omitempty"`
+}
+
+// ReleaseSummary describes the deployed revision of a release
+type ReleaseSummary struct {
+	Revision   int         `json:"revision"`
+	Chart      string      `json:"chart"`
+	Version    string      `json:"version"`
+	AppVersion string      `json:"appVersion,omitempty"`
+	Deployed   metav1.Time `json:"deployed,omitempty"`
+	// Notes are the rendered notes of the chart
+	Notes string `json:"notes,omitempty"`
+	// ResourceCount is the number of resources in the manifest of the revision
+	ResourceCount int                `json:"resourceCount"`
+	Resources     []DeployedResource `json:"resources,omitempty"`
+	// ConfigMap contains the notes and resources instead of the status if they exceed its limits
+	ConfigMap string `json:"configMap,omitempty"`
+}
+
+// DeployedResource represents a resource in the manifest of a revision
+type DeployedResource struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	// Namespace is empty for cluster scoped resources and resources without a namespace in the manifest
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// ReleaseTests defines the execution of the test hooks of a chart
+type ReleaseTests struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// Timeout in seconds for running the tests. Default is 300
+	Timeout int64 `json:"timeout,omitempty"`
+	// AfterDeploy runs the tests after each install, upgrade or rollback
+	AfterDeploy bool `json:"afterDeploy,omitempty"`
+	// RollbackOnFailure rolls the release back to the last successful revision if a test fails
+	RollbackOnFailure bool `json:"rollbackOnFailure,omitempty"`
+}
+
+// ReleaseTestStatus represents the results of the last test run of a release
+type ReleaseTestStatus struct {
+	Revision int    `json:"revision"`
+	Phase    string `json:"phase"`
+	// Trigger is the last handled value of the test annotation
+	Trigger string `json:"trigger,omitempty"`
+	// ConfigMap is the name of the configmap which contains the logs of the test pods
+	ConfigMap string              `json:"configMap,omitempty"`
+	Results   []ReleaseTestResult `json:"results,omitempty"`
+	// RolledBackGeneration is set if the release was rolled back after failed tests of this generation
+	RolledBackGeneration int64 `json:"rolledBackGeneration,omitempty"`
+}
+
+// ReleaseTestResult represents the result of a single test hook
+type ReleaseTestResult struct {
+	Name      string      `json:"name"`
+	Phase     string      `json:"phase"`
+	Started   metav1.Time `json:"started,omitempty"`
+	Completed metav1.Time `json:"completed,omitempty"`
+}
+
+// ClusterStatus represents the reachability of the cluster a release is deployed to
+type ClusterStatus struct {
+	Name               string      `json:"name"`
+	Server             string      `json:"server,omitempty"`
+	Reachable          bool        `json:"reachable"`
+	Message            string      `json:"message,omitempty"`
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// ReleasePlan summarizes the pending changes of a release in plan mode
+type ReleasePlan struct {
+	// ConfigMap is the name of the configmap which contains the diff
+	ConfigMap string `json:"configMap,omitempty"`
+	// Digest identifies the pending changes and has to be set in the approval annotation
+	Digest string `json:"digest,omitempty"`
+	// Inputs identifies the deployed revision, chart, values and post renderer the plan was rendered for
+	Inputs  string   `json:"inputs,omitempty"`
+	Added   []string `json:"added,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// ReleaseRollback records a rollback which was applied for the rollbackTo field of the spec
+type ReleaseRollback struct {
+	// Revision is the revision the release was rolled back to
+	Revision int `json:"revision"`
+	// Generation is the generation of the release resource the rollback was applied for
+	Generation int64 `json:"generation"`
+	// DeployedRevision is the revision which was created by the rollback
+	DeployedRevision int `json:"deployedRevision"`
+}
+
+// RemediationPolicy defines retries of failed installs and upgrades and what happens once they are exhausted
+type RemediationPolicy struct {
+	// Retries is the number of retries after a failed install or upgrade
+	Retries int `json:"retries,omitempty"`
+	// Backoff is the delay in seconds before the first retry which doubles with each retry. Default is 10
+	Backoff int64 `json:"backoff,omitempty"`
+	// RollbackOnFailure rolls a failed upgrade back to the last successful revision
+	RollbackOnFailure bool `json:"rollbackOnFailure,omitempty"`
+	// UninstallOnFailure uninstalls a failed install
+	UninstallOnFailure bool `json:"uninstallOnFailure,omitempty"`
+}
+
+// ReleaseRevision represents a helm revision of the release
+type ReleaseRevision struct {
+	Revision    int         `json:"revision"`
+	Chart       string      `json:"chart"`
+	Version     string      `json:"version"`
+	AppVersion  string      `json:"appVersion,omitempty"`
+	Status      string      `json:"status"`
+	Updated     metav1.Time `json:"updated,omitempty"`
+	Description string      `json:"description,omitempty"`
+}
+
+// DriftDetection defines how often drift is detected and if it is reverted
+type DriftDetection struct {
+	// Interval in seconds between two checks. Default is 300
+	Interval int64 `json:"interval,omitempty"`
+	// SelfHeal reverts detected drift by upgrading the release with the deployed chart and values
+	SelfHeal bool `json:"selfHeal,omitempty"`
+}
+
+// DriftedResource represents a live object which differs from the manifest of the deployed revision
+type DriftedResource struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// Reason is either missing or the path of the first field which differs
+	Reason string `json:"reason"`
+}
+
+// UpgradePolicy defines which newer chart versions are applied automatically
+type UpgradePolicy struct {
+	// Type limits automatic upgrades to patch or minor versions or allows any version
+	// +kubebuilder:validation:Enum=none;patch;minor;any
+	Type string `json:"type,omitempty"`
+	// MaintenanceWindow restricts automatic upgrades to a time window
+	MaintenanceWindow *MaintenanceWindow `json:"maintenanceWindow,omitempty"`
+}
+
+// MaintenanceWindow represents a daily time window in UTC
+type MaintenanceWindow struct {
+	// Start and end of the window formatted as HH:MM
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	Start string `json:"start"`
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	End string `json:"end"`
+	// Days limits the window to weekdays like Mon or Sat
+	Days []Weekday `json:"days,omitempty"`
+}
+
+// Weekday is the abbreviated name of a day of the week
+// +kubebuilder:validation:Enum=Mon;Tue;Wed;Thu;Fri;Sat;Sun
+type Weekday string
+
 // ReleaseStatus defines the observed state of Release
 type ReleaseStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
@@ -46,6 +307,35 @@ type ReleaseStatus struct {
 	Status     *string            `json:"status,omitempty"`
 	Revision   *int               `json:"revision,omitempty"`
 	Conditions []metav1.Condition `json:"conditions"`
+	// RequestedVersion is the version or constraint of the spec
+	RequestedVersion string `json:"requestedVersion,omitempty"`
+	// ResolvedVersion is the chart version of the deployed release
+	ResolvedVersion string `json:"resolvedVersion,omitempty"`
+	// Drift lists the resources which differ from the manifest of the deployed revision
+	Drift []DriftedResource `json:"drift,omitempty"`
+	// LastDriftCheck is the time of the last drift detection
+	LastDriftCheck *metav1.Time `json:"lastDriftCheck,omitempty"`
+	// History lists the latest helm revisions of the release starting with the newest
+	History []ReleaseRevision `json:"history,omitempty"`
+	// Failures counts the failed installs or upgrades of the generation in FailedGeneration
+	Failures         int   `json:"failures,omitempty"`
+	FailedGeneration int64 `json:"failedGeneration,omitempty"`
+	// NextRetryAt is the time before which a failed install or upgrade is not retried
+	NextRetryAt *metav1.Time `json:"nextRetryAt,omitempty"`
+	// Plan lists the pending changes if plan mode is enabled
+	Plan *ReleasePlan `json:"plan,omitempty"`
+	// Rollback is the last applied rollback to the revision of the spec
+	Rollback *ReleaseRollback `json:"rollback,omitempty"`
+	// CreatedNamespace is the namespace which was created for the release and is deleted with it
+	CreatedNamespace string `json:"createdNamespace,omitempty"`
+	// Cluster shows whether the remote cluster of the release is reachable
+	Cluster *ClusterStatus `json:"cluster,omitempty"`
+	// Tests shows the results of the last test run
+	Tests *ReleaseTestStatus `json:"tests,omitempty"`
+	// Health lists the assessed resources of the deployed revision
+	Health []ResourceHealth `json:"health,omitempty"`
+	// Summary describes the deployed revision with its notes and resources
+	Summary *ReleaseSummary `json:"summary,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -54,6 +344,7 @@ type ReleaseStatus struct {
 // +kubebuilder:printcolumn:name="Repo",type="string",JSONPath=`.spec.repo`
 // +kubebuilder:printcolumn:name="Chart",type="string",JSONPath=`.spec.chart`
 // +kubebuilder:printcolumn:name="Version",type="string",JSONPath=`.spec.version`
+// +kubebuilder:printcolumn:name="Resolved",type="string",JSONPath=`.status.resolvedVersion`
 // +kubebuilder:printcolumn:name="Synced",type="string",JSONPath=`.status.synced`
 // +kubebuilder:printcolumn:name="Status",type="string",JSONPath=`.status.status`
 // +kubebuilder:printcolumn:name="Revision",type="number",JSONPath=`.status.revision`
@@ -32,12 +32,15 @@ type ReleaseGroupSpec struct {
 	LabelSelector string            `json:"labelSelector"`
 	Releases      []ReleaseSpec     `json:"releases"`
 	Env           map[string]string `json:"env,omitempty"`
+	// Suspend stops creating and removing releases of the group and suspends its releases until it is unset
+	Suspend bool `json:"suspend,omitempty"`
 }
 
 // ReleaseGroupStatus defines the observed state of ReleaseGroup
 type ReleaseGroupStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
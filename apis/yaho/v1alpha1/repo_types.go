@@ -33,6 +33,61 @@ type RepositorySpec struct {
 	Charts     []Entry `json:"charts,omitempty"`
 	Sync       Sync    `json:"sync,omitempty"`
 	AuthSecret string  `json:"authSecret,omitempty"`
+	// PassCredentialsAll passes the credentials of the auth secret also to chart urls on other hosts than the repository
+	PassCredentialsAll bool `json:"passCredentialsAll,omitempty"`
+	// PlainHTTP uses insecure http connections for oci registries
+	PlainHTTP bool `json:"plainHTTP,omitempty"`
+	// TLSSecret is a secret with ca.crt, tls.crt and tls.key for connections to the repository
+	TLSSecret string `json:"tlsSecret,omitempty"`
+	// InsecureSkipTLSVerify disables verification of the repository server certificate
+	InsecureSkipTLSVerify bool `json:"insecureSkipTLSVerify,omitempty"`
+	// Git uses the url as git repository and packages the charts found in it
+	Git *GitSource `json:"git,omitempty"`
+	// Filter limits the charts and versions of the index which are synced
+	Filter *ChartFilter `json:"filter,omitempty"`
+	// Storage selects the backend for the files of chart versions
+	Storage *ChartStorage `json:"storage,omitempty"`
+	// Verify enables provenance verification of all charts of the repository
+	Verify *ChartVerification `json:"verify,omitempty"`
+	// Suspend stops syncing the index and the charts of the repository until it is unset
+	Suspend bool `json:"suspend,omitempty"`
+}
+
+// ChartVerification enables verification of downloaded chart archives against their provenance files
+type ChartVerification struct {
+	// KeyringSecret is a secret with the public keyring in the key keyring.gpg
+	KeyringSecret string `json:"keyringSecret"`
+}
+
+// ChartStorage defines where templates and crds of chart versions are stored
+type ChartStorage struct {
+	// Type is configmap for a configmap per template directory, compressed or secret for gzipped archives split into chunks
+	// or filesystem for caching chart archives in a directory like a mounted persistent volume
+	// +kubebuilder:validation:Enum=configmap;compressed;secret;filesystem
+	Type string `json:"type,omitempty"`
+	// Path is the directory of the filesystem backend
+	Path string `json:"path,omitempty"`
+}
+
+// ChartFilter selects charts and versions of a repository index
+type ChartFilter struct {
+	// Include and Exclude are glob patterns or regular expressions enclosed in slashes
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+	// Versions maps chart names to semver constraints for their versions
+	Versions map[string]string `json:"versions,omitempty"`
+}
+
+// GitSource defines which revision of a git repository is used as chart source
+type GitSource struct {
+	// Branch is checked out if neither tag nor commit is set
+	Branch string `json:"branch,omitempty"`
+	// Tag takes precedence over branch
+	Tag string `json:"tag,omitempty"`
+	// Commit takes precedence over tag and branch
+	Commit string `json:"commit,omitempty"`
+	// Path is the directory which is searched for charts
+	Path string `json:"path,omitempty"`
 }
 
 // RepositoryStatus defines the observed state of Repo
@@ -42,6 +97,16 @@ type RepositoryStatus struct {
 	Synced     *bool              `json:"synced,omitempty"`
 	Charts     *int64             `json:"charts,omitempty"`
 	Conditions []metav1.Condition `json:"conditions"`
+	// LastSyncTime is the time of the last successful index sync
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+	// Digest is the sha256 digest of the last synced index
+	Digest string `json:"digest,omitempty"`
+	// ETag and LastModified are sent for conditional requests of an index file
+	ETag               string `json:"etag,omitempty"`
+	LastModified       string `json:"lastModified,omitempty"`
+	ObservedGeneration int64  `json:"observedGeneration,omitempty"`
+	// SkippedCharts maps charts of the index which are not synced to the reason
+	SkippedCharts map[string]string `json:"skippedCharts,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -50,6 +115,7 @@ type RepositoryStatus struct {
 // +kubebuilder:printcolumn:name="Group",type="string",JSONPath=`.metadata.labels['repoGroup']`
 // +kubebuilder:printcolumn:name="Synced",type="boolean",JSONPath=".status.synced"
 // +kubebuilder:printcolumn:name="Charts",type="integer",JSONPath=".status.charts"
+// +kubebuilder:printcolumn:name="Last Sync",type="date",JSONPath=".status.lastSyncTime"
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // Repository is the Schema for the repos API
@@ -64,6 +130,8 @@ type Repository struct {
 type Entry struct {
 	Name     string   `json:"name,omitempty"`
 	Versions []string `json:"versions,omitempty"`
+	// Path is the chart directory within a git repository
+	Path string `json:"path,omitempty"`
 }
 
 // +kubebuilder:object:root=true
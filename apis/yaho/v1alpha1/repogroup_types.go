@@ -32,6 +32,8 @@ type RepoGroupSpec struct {
 	LabelSelector string            `json:"labelSelector"`
 	Repos         []RepositorySpec  `json:"repos"`
 	Env           map[string]string `json:"env,omitempty"`
+	// Filter is used for repositories of the group which have no own filter
+	Filter *ChartFilter `json:"filter,omitempty"`
 }
 
 // RepoGroupStatus defines the observed state of RepoGroup
@@ -19,7 +19,10 @@ package helm
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -27,6 +30,8 @@ import (
 	"github.com/soer3n/yaho/internal/release"
 	"github.com/soer3n/yaho/internal/utils"
 	"helm.sh/helm/v3/pkg/cli"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	meta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -35,12 +40,22 @@ import (
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
+// testAnnotation triggers a test run of a release whenever its value changes
+const testAnnotation = "yaho.soer3n.dev/test"
+
+// planApprovalAnnotation has to be set to the digest of a plan to apply its changes
+const planApprovalAnnotation = "yaho.soer3n.dev/approve"
+
 // ReleaseReconciler reconciles a Release object
 type ReleaseReconciler struct {
 	client.WithWatch
@@ -49,12 +64,17 @@ type ReleaseReconciler struct {
 	Log            logr.Logger
 	Scheme         *runtime.Scheme
 	Recorder       record.EventRecorder
+	testRuns       release.TestRuns
 }
 
 // +kubebuilder:rbac:groups=yaho.soer3n.dev,resources=releases,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups=yaho.soer3n.dev,resources=values,verbs=get;list;watch;patch
-// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
-// +kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch
+// +kubebuilder:rbac:groups=yaho.soer3n.dev,resources=releasegroups,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch;impersonate
+// +kubebuilder:rbac:groups="",resources=serviceaccounts/token,verbs=create
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;create;delete
 // +kubebuilder:rbac:groups=yaho.soer3n.dev,resources=releases/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=yaho.soer3n.dev,resources=releases/finalizers,verbs=update
 
@@ -90,6 +110,26 @@ func (r *ReleaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return ctrl.Result{}, err
 	}
 
+	// deletions are handled while the release is suspended
+	suspended, message, err := isReleaseSuspended(ctx, r.WithWatch, instance)
+
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	suspended = suspended && instance.GetDeletionTimestamp() == nil
+
+	if utils.SetSuspendedCondition(&instance.Status.Conditions, suspended, message) {
+		if err := r.Status().Update(ctx, instance); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if suspended {
+		reqLogger.Info("reconciliation of release is suspended", "reason", message)
+		return ctrl.Result{}, nil
+	}
+
 	var requeue bool
 
 	g := http.Client{
@@ -108,21 +148,62 @@ func (r *ReleaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 
 	config, err := r.getConfig(instance.Spec, instance.ObjectMeta.Namespace)
 
+	if err != nil {
+		r.Log.Info(err.Error())
+	}
+
+	cluster := instance.Spec.Cluster
+
+	if cluster == nil && config != nil {
+		cluster = config.Spec.Cluster
+	}
+
 	var releaseRestGetter genericclioptions.RESTClientGetter
 	kubeconfig := ""
 
-	if err == nil {
-		releaseRestGetter, err = utils.NewRESTClientGetter(config, instance.ObjectMeta.Namespace, *releaseNamespace, r.IsLocal, r.WithWatch, r.Log)
+	if err == nil || cluster != nil {
+		getter, err := utils.NewRESTClientGetter(config, cluster, instance.ObjectMeta.Namespace, *releaseNamespace, r.IsLocal, r.WithWatch, r.Log)
+
+		if cluster != nil {
+			if err == nil {
+				err = getter.IsReachable()
+			}
+
+			if statusErr := r.syncCluster(ctx, instance, cluster, getter, err); statusErr != nil {
+				return ctrl.Result{}, statusErr
+			}
+
+			if err != nil {
+				reqLogger.Info("cluster of release not reachable", "cluster", cluster.Name, "error", err.Error())
+
+				if instance.GetDeletionTimestamp() != nil {
+					return r.handleBlockedDeletion(ctx, instance, config, err)
+				}
+
+				return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+			}
+		}
 
 		if err != nil {
 			r.Log.Info(err.Error())
+
+			if instance.GetDeletionTimestamp() != nil {
+				return r.handleBlockedDeletion(ctx, instance, config, err)
+			}
+
 			return ctrl.Result{}, err
 		}
 
-		casted := releaseRestGetter.(*utils.HelmRESTClientGetter)
-		kubeconfig = casted.KubeConfig
-	} else {
-		r.Log.Info(err.Error())
+		releaseRestGetter = getter
+		kubeconfig = getter.KubeConfig
+	}
+
+	if cluster == nil && instance.Status.Cluster != nil {
+		instance.Status.Cluster = nil
+
+		if err := r.Status().Update(ctx, instance); err != nil {
+			return ctrl.Result{}, err
+		}
 	}
 
 	synced := false
@@ -144,13 +225,18 @@ func (r *ReleaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		initRevision := 0
 		instance.Status.Revision = &initRevision
 
-		if err := r.syncStatus(ctx, instance, metav1.ConditionFalse, "initResource", "start struct initialization", status, synced, initRevision); err != nil {
+		if err := r.syncStatus(ctx, instance, metav1.ConditionFalse, "initResource", "start struct initialization", status, synced, initRevision, instance.Status.ResolvedVersion); err != nil {
 			return ctrl.Result{}, err
 		}
 	}
 
 	if err != nil {
 		reqLogger.Info(err.Error(), "error on init struct", err.Error())
+
+		if instance.GetDeletionTimestamp() != nil {
+			return r.handleBlockedDeletion(ctx, instance, config, err)
+		}
+
 		status := "initError"
 
 		if *instance.Status.Status == status {
@@ -159,7 +245,7 @@ func (r *ReleaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 
 		instance.Status.Status = &status
 
-		if err := r.syncStatus(ctx, instance, metav1.ConditionFalse, "initError", err.Error(), status, synced, helmRelease.Revision); err != nil {
+		if err := r.syncStatus(ctx, instance, metav1.ConditionFalse, "initError", err.Error(), status, synced, helmRelease.Revision, instance.Status.ResolvedVersion); err != nil {
 			return ctrl.Result{}, err
 		}
 
@@ -168,33 +254,124 @@ func (r *ReleaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 
 	isRepoMarkedToBeDeleted := instance.GetDeletionTimestamp() != nil
 
+	if isRepoMarkedToBeDeleted && controllerutil.ContainsFinalizer(instance, "finalizer.releases.yaho.soer3n.dev") {
+		dependents, err := r.getDependents(ctx, instance)
+
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		// dependent releases are uninstalled first
+		if len(dependents) > 0 {
+			message := fmt.Sprintf("waiting for deletion of dependent releases %v", strings.Join(dependents, ", "))
+
+			if err := r.syncStatus(ctx, instance, metav1.ConditionFalse, "waitingForDependents", message, "waitingForDependents", synced, helmRelease.Revision, instance.Status.ResolvedVersion); err != nil {
+				return ctrl.Result{}, err
+			}
+
+			return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+		}
+
+		if err := helmRelease.RemoveRelease(); err != nil {
+			giveUpAfter := release.GetUninstallGiveUpAfter(helmRelease.Uninstall)
+
+			if time.Since(instance.GetDeletionTimestamp().Time) < giveUpAfter {
+				reqLogger.Info("error on uninstalling release", "error", err.Error())
+
+				if err := r.syncStatus(ctx, instance, metav1.ConditionFalse, "uninstallFailed", err.Error(), "uninstallFailed", synced, helmRelease.Revision, instance.Status.ResolvedVersion); err != nil {
+					return ctrl.Result{}, err
+				}
+
+				return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+			}
+
+			reqLogger.Info("giving up uninstall of release", "giveUpAfter", giveUpAfter, "error", err.Error())
+		}
+	}
+
 	if requeue, err = r.handleFinalizer(helmRelease, instance, isRepoMarkedToBeDeleted); err != nil {
 		reqLogger.Error(err, "Handle finalizer for release %v failed.", helmRelease.Name)
 		return ctrl.Result{}, err
 	}
 
 	if requeue {
-		if isRepoMarkedToBeDeleted {
-			if err := helmRelease.RemoveRelease(); err != nil {
-				return ctrl.Result{}, err
-			}
-		}
 		reqLogger.Info("Update resource after modifying finalizer.")
 		if err := r.Update(context.TODO(), instance); err != nil {
 			reqLogger.Error(err, "error in reconciling")
 			return ctrl.Result{}, err
 		}
+
+		if isRepoMarkedToBeDeleted {
+			r.testRuns.Forget(req.NamespacedName.String())
+			return ctrl.Result{}, nil
+		}
 	}
 
 	if instance.Spec.Values == nil {
 		instance.Spec.Values = []string{}
 	}
 
+	if policy := helmRelease.Remediation; policy != nil && instance.Status.FailedGeneration == instance.Generation && instance.Status.Failures > policy.Retries {
+		reqLogger.Info("retries of release exhausted. Waiting for spec change.", "failures", instance.Status.Failures)
+		return ctrl.Result{}, nil
+	}
+
+	// other events must not retry a failed install or upgrade before its backoff has passed
+	if wait := release.GetRetryWait(instance, time.Now()); wait > 0 {
+		reqLogger.Info("waiting for backoff of failed release.", "failures", instance.Status.Failures, "wait", wait)
+		return ctrl.Result{RequeueAfter: wait}, nil
+	}
+
+	if tests := instance.Status.Tests; tests != nil && tests.RolledBackGeneration != 0 && tests.RolledBackGeneration == instance.Generation {
+		reqLogger.Info("release rolled back after failed tests. Waiting for spec change.", "revision", tests.Revision)
+		return ctrl.Result{}, nil
+	}
+
+	if len(instance.Spec.DependsOn) > 0 {
+		ready, err := r.syncDependencies(ctx, instance, helmRelease)
+
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if !ready {
+			return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+		}
+	}
+
+	if instance.Spec.Plan && helmRelease.RollbackTo == nil {
+		approved, err := r.syncPlan(ctx, instance, helmRelease)
+
+		if err != nil {
+			reqLogger.Info("error on planning release", "error", err.Error())
+
+			if err := r.syncStatus(ctx, instance, metav1.ConditionFalse, "planFailed", err.Error(), "planFailed", synced, helmRelease.Revision, instance.Status.ResolvedVersion); err != nil {
+				return ctrl.Result{}, err
+			}
+
+			return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
+		}
+
+		if !approved {
+			message := fmt.Sprintf("plan %v waits for approval by annotation %v", instance.Status.Plan.Digest, planApprovalAnnotation)
+
+			if err := r.syncStatus(ctx, instance, metav1.ConditionFalse, "pendingApproval", message, "pendingApproval", synced, helmRelease.Revision, instance.Status.ResolvedVersion); err != nil {
+				return ctrl.Result{}, err
+			}
+
+			return ctrl.Result{}, nil
+		}
+	}
+
 	if err := helmRelease.Update(); err != nil {
+		if helmRelease.Remediation != nil {
+			return r.handleFailure(ctx, instance, helmRelease, err)
+		}
+
 		status := "updateFailed"
 		instance.Status.Status = &status
 
-		if err := r.syncStatus(ctx, instance, metav1.ConditionFalse, "updateFailed", err.Error(), status, synced, helmRelease.Revision); err != nil {
+		if err := r.syncStatus(ctx, instance, metav1.ConditionFalse, "updateFailed", err.Error(), status, synced, helmRelease.Revision, instance.Status.ResolvedVersion); err != nil {
 			reqLogger.Info(err.Error())
 		}
 
@@ -203,15 +380,574 @@ func (r *ReleaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 
 	status := "success"
 	synced = true
+	resolvedVersion := helmRelease.Chart.Metadata.Version
+
+	// the deployed chart differs from the loaded one after a rollback
+	if len(helmRelease.History) > 0 {
+		resolvedVersion = helmRelease.History[0].Version
+	}
+
+	instance.Status.Failures = 0
+	instance.Status.FailedGeneration = 0
+	instance.Status.NextRetryAt = nil
+
+	if err := r.syncStatus(ctx, instance, metav1.ConditionTrue, "success", "all up to date", status, synced, helmRelease.Revision, resolvedVersion); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !equality.Semantic.DeepEqual(instance.Status.History, helmRelease.History) || instance.Status.CreatedNamespace != helmRelease.CreatedNamespace || !equality.Semantic.DeepEqual(instance.Status.Rollback, helmRelease.Rollback) {
+		instance.Status.History = helmRelease.History
+		instance.Status.CreatedNamespace = helmRelease.CreatedNamespace
+		instance.Status.Rollback = helmRelease.Rollback
+
+		if err := r.Status().Update(ctx, instance); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if err := r.syncSummary(ctx, instance, helmRelease); err != nil {
+		reqLogger.Info("error on release summary", "error", err.Error())
+	}
+
+	result := ctrl.Result{}
+
+	if shouldRunTests(instance, helmRelease.Revision) {
+		running, err := r.runTests(ctx, instance, helmRelease)
+
+		if err != nil {
+			reqLogger.Info("error on running tests", "error", err.Error())
+		}
+
+		if running {
+			result.RequeueAfter = 10 * time.Second
+			reqLogger.Info("Reconcile release until its tests finished.", "interval", result.RequeueAfter)
+		}
+	}
+
+	healthy, err := r.syncHealth(ctx, instance, helmRelease)
+
+	if err != nil {
+		reqLogger.Info("error on health assessment", "error", err.Error())
+	}
+
+	if interval := getHealthInterval(instance); (err != nil || !healthy) && (result.RequeueAfter == 0 || interval < result.RequeueAfter) {
+		result.RequeueAfter = interval
+		reqLogger.Info("Reconcile release until it is healthy.", "interval", result.RequeueAfter)
+	}
+
+	if instance.Spec.Drift != nil {
+		if err := r.syncDrift(ctx, instance, helmRelease); err != nil {
+			reqLogger.Info("error on drift detection", "error", err.Error())
+		}
+
+		if interval := getDriftInterval(instance); result.RequeueAfter == 0 || interval < result.RequeueAfter {
+			result.RequeueAfter = interval
+			reqLogger.Info("Reconcile release for drift detection.", "interval", result.RequeueAfter)
+		}
+	}
+
+	if helmRelease.UpgradeDelay > 0 && (result.RequeueAfter == 0 || helmRelease.UpgradeDelay < result.RequeueAfter) {
+		reqLogger.Info("Reconcile release for pending upgrade.", "delay", helmRelease.UpgradeDelay)
+		result.RequeueAfter = helmRelease.UpgradeDelay
+	}
+
+	if result.RequeueAfter == 0 {
+		reqLogger.Info("Don't reconcile releases.")
+	}
+
+	return result, nil
+}
+
+// isReleaseSuspended returns whether the release or its release group is suspended and why
+func isReleaseSuspended(ctx context.Context, c client.Client, instance *helmv1alpha1.Release) (bool, string, error) {
+
+	if instance.Spec.Suspend {
+		return true, "release is suspended", nil
+	}
+
+	group, ok := instance.GetLabels()["releaseGroup"]
+
+	if !ok {
+		return false, "", nil
+	}
+
+	groups := &helmv1alpha1.ReleaseGroupList{}
+
+	if err := c.List(ctx, groups, client.InNamespace(instance.ObjectMeta.Namespace)); err != nil {
+		return false, "", err
+	}
+
+	for _, item := range groups.Items {
+		if item.Spec.LabelSelector == group && item.Spec.Suspend {
+			return true, fmt.Sprintf("release group %v is suspended", item.Name), nil
+		}
+	}
+
+	return false, "", nil
+}
+
+// syncDependencies returns whether all dependencies of the release are ready and updates the status if not
+func (r *ReleaseReconciler) syncDependencies(ctx context.Context, instance *helmv1alpha1.Release, helmRelease *release.Release) (bool, error) {
+	cycle, err := release.GetDependencyCycle(instance, r.getDependency)
+
+	if err != nil {
+		return false, err
+	}
+
+	if cycle != nil {
+		message := fmt.Sprintf("dependency cycle %v", strings.Join(cycle, " -> "))
+		r.Log.Info("release is part of a dependency cycle. Waiting for spec change.", "cycle", message)
+
+		return false, r.syncStatus(ctx, instance, metav1.ConditionFalse, "dependencyCycle", message, "dependencyCycle", false, helmRelease.Revision, instance.Status.ResolvedVersion)
+	}
+
+	message, err := release.GetUnreadyDependency(instance, r.getDependency)
+
+	if err != nil {
+		return false, err
+	}
+
+	if message == "" {
+		return true, nil
+	}
+
+	r.Log.Info("waiting for dependencies of release", "message", message)
+	return false, r.syncStatus(ctx, instance, metav1.ConditionFalse, "dependencyNotReady", message, "waitingForDependencies", false, helmRelease.Revision, instance.Status.ResolvedVersion)
+}
+
+// getDependency returns the release resource of a dependency or nil if it doesn't exist
+func (r *ReleaseReconciler) getDependency(key types.NamespacedName) (*helmv1alpha1.Release, error) {
+	dependency := &helmv1alpha1.Release{}
+
+	if err := r.Get(context.Background(), key, dependency); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return dependency, nil
+}
+
+// getDependents returns the releases which depend on the release
+func (r *ReleaseReconciler) getDependents(ctx context.Context, instance *helmv1alpha1.Release) ([]string, error) {
+	releases := &helmv1alpha1.ReleaseList{}
+
+	if err := r.List(ctx, releases, client.InNamespace(r.WatchNamespace)); err != nil {
+		return nil, err
+	}
+
+	return release.GetDependents(instance, releases.Items), nil
+}
+
+// syncCluster updates the reachability of the remote cluster of the release in the status
+func (r *ReleaseReconciler) syncCluster(ctx context.Context, instance *helmv1alpha1.Release, cluster *helmv1alpha1.ClusterReference, getter *utils.HelmRESTClientGetter, err error) error {
+	status := &helmv1alpha1.ClusterStatus{
+		Name:      cluster.Name,
+		Reachable: err == nil,
+	}
+
+	if getter != nil {
+		status.Server = getter.Server
+	}
+
+	if err != nil {
+		status.Message = err.Error()
+	}
+
+	if current := instance.Status.Cluster; current != nil {
+		status.LastTransitionTime = current.LastTransitionTime
+
+		if equality.Semantic.DeepEqual(current, status) {
+			return nil
+		}
+	}
+
+	status.LastTransitionTime = metav1.Now()
+	instance.Status.Cluster = status
+
+	return r.Status().Update(ctx, instance)
+}
+
+// syncPlan stores the pending changes of the release in a configmap and returns whether they are approved.
+// A pending plan is kept as long as its inputs don't change, so that its digest can be approved.
+func (r *ReleaseReconciler) syncPlan(ctx context.Context, instance *helmv1alpha1.Release, helmRelease *release.Release) (bool, error) {
+	inputs, err := helmRelease.PlanInputs()
+
+	if err != nil {
+		return false, err
+	}
+
+	if pinned := instance.Status.Plan; pinned != nil && pinned.Digest != "" && pinned.Inputs == inputs {
+		return instance.GetAnnotations()[planApprovalAnnotation] == pinned.Digest, nil
+	}
+
+	plan, err := helmRelease.Plan()
+
+	if err != nil {
+		return false, err
+	}
+
+	configmap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "helm-plan-" + instance.GetName(),
+			Namespace: instance.GetNamespace(),
+		},
+	}
+
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.WithWatch, configmap, func() error {
+		configmap.Data = map[string]string{
+			"digest": plan.Digest,
+			"diff":   plan.Diff,
+		}
+
+		return controllerutil.SetControllerReference(instance, configmap, r.Scheme)
+	}); err != nil {
+		return false, err
+	}
+
+	plan.ConfigMap = configmap.GetName()
+
+	if !equality.Semantic.DeepEqual(instance.Status.Plan, &plan.ReleasePlan) {
+		instance.Status.Plan = &plan.ReleasePlan
+
+		if err := r.Status().Update(ctx, instance); err != nil {
+			return false, err
+		}
+	}
+
+	// nothing to approve if the deployed revision is up to date
+	if plan.Digest == "" {
+		return true, nil
+	}
+
+	return instance.GetAnnotations()[planApprovalAnnotation] == plan.Digest, nil
+}
+
+// handleFailure counts failed installs and upgrades of the current generation and retries them with exponential backoff.
+// Once the retries are exhausted the release is remediated and not reconciled again until the spec changes.
+func (r *ReleaseReconciler) handleFailure(ctx context.Context, instance *helmv1alpha1.Release, helmRelease *release.Release, updateErr error) (ctrl.Result, error) {
+	policy := helmRelease.Remediation
+	synced := false
+
+	if instance.Status.FailedGeneration != instance.Generation {
+		instance.Status.Failures = 0
+	}
+
+	instance.Status.Failures++
+	instance.Status.FailedGeneration = instance.Generation
+
+	if instance.Status.Failures <= policy.Retries {
+		delay := release.GetRemediationDelay(policy, instance.Status.Failures)
+		message := fmt.Sprintf("attempt %v of %v failed: %v", instance.Status.Failures, policy.Retries+1, updateErr.Error())
+		instance.Status.NextRetryAt = &metav1.Time{Time: time.Now().Add(delay)}
+
+		r.Log.Info("retry failed release", "release", instance.GetName(), "delay", delay)
+
+		if err := r.syncStatus(ctx, instance, metav1.ConditionFalse, "updateFailed", message, "updateFailed", synced, helmRelease.Revision, instance.Status.ResolvedVersion); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{RequeueAfter: delay}, nil
+	}
 
-	if err := r.syncStatus(ctx, instance, metav1.ConditionTrue, "success", "all up to date", status, synced, helmRelease.Revision); err != nil {
+	status := "retriesExhausted"
+	message := fmt.Sprintf("%v attempts failed: %v", instance.Status.Failures, updateErr.Error())
+	instance.Status.NextRetryAt = nil
+	action, err := helmRelease.Remediate()
+
+	if err != nil {
+		message = fmt.Sprintf("%v; remediation failed: %v", message, err.Error())
+	} else if action != "" {
+		status = action
+	}
+
+	r.Log.Info("retries of release exhausted", "release", instance.GetName(), "status", status)
+
+	if err := r.syncStatus(ctx, instance, metav1.ConditionFalse, "retriesExhausted", message, status, synced, helmRelease.Revision, instance.Status.ResolvedVersion); err != nil {
 		return ctrl.Result{}, err
 	}
 
-	reqLogger.Info("Don't reconcile releases.")
 	return ctrl.Result{}, nil
 }
 
+// syncDrift detects drift of the deployed release, reverts it if self healing is enabled and updates the status
+func (r *ReleaseReconciler) syncDrift(ctx context.Context, instance *helmv1alpha1.Release, helmRelease *release.Release) error {
+	drift, err := helmRelease.DetectDrift()
+
+	if err != nil {
+		return err
+	}
+
+	// healing would upgrade a release which is rolled back
+	if len(drift) > 0 && instance.Spec.Drift.SelfHeal && instance.Spec.RollbackTo == nil {
+		if err := helmRelease.Heal(); err != nil {
+			return err
+		}
+
+		if drift, err = helmRelease.DetectDrift(); err != nil {
+			return err
+		}
+	}
+
+	condition := metav1.Condition{Type: "drifted", Status: metav1.ConditionFalse, Reason: "noDrift", Message: "live objects match the deployed revision"}
+
+	if len(drift) > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "driftDetected"
+		condition.Message = fmt.Sprintf("%v resources differ from the deployed revision", len(drift))
+	}
+
+	if len(drift) == 0 {
+		drift = nil
+	}
+
+	changed := meta.SetStatusCondition(&instance.Status.Conditions, condition)
+
+	if !changed && reflect.DeepEqual(drift, instance.Status.Drift) {
+		return nil
+	}
+
+	instance.Status.Drift = drift
+	instance.Status.LastDriftCheck = &metav1.Time{Time: time.Now()}
+
+	return r.Status().Update(ctx, instance)
+}
+
+// runTests executes the test hooks of the release in the background and returns whether they are still running.
+// Once they finished the logs of the test pods are stored in a configmap and the status is updated.
+func (r *ReleaseReconciler) runTests(ctx context.Context, instance *helmv1alpha1.Release, helmRelease *release.Release) (bool, error) {
+	timeout := 300 * time.Second
+
+	if instance.Spec.Tests.Timeout > 0 {
+		timeout = time.Duration(instance.Spec.Tests.Timeout) * time.Second
+	}
+
+	key := types.NamespacedName{Namespace: instance.GetNamespace(), Name: instance.GetName()}.String()
+	result, err := r.testRuns.Run(key, helmRelease, timeout, instance.GetAnnotations()[testAnnotation])
+
+	if err != nil {
+		return false, err
+	}
+
+	if result == nil {
+		condition := metav1.Condition{Type: "tested", Status: metav1.ConditionUnknown, Reason: "testsRunning", Message: fmt.Sprintf("tests of revision %v are running", helmRelease.Revision)}
+
+		if meta.SetStatusCondition(&instance.Status.Conditions, condition) {
+			return true, r.Status().Update(ctx, instance)
+		}
+
+		return true, nil
+	}
+
+	configmap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "helm-test-" + instance.GetName(),
+			Namespace: instance.GetNamespace(),
+		},
+	}
+
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.WithWatch, configmap, func() error {
+		configmap.Data = result.Logs
+		return controllerutil.SetControllerReference(instance, configmap, r.Scheme)
+	}); err != nil {
+		return false, err
+	}
+
+	status := result.ReleaseTestStatus
+	status.ConfigMap = configmap.GetName()
+
+	condition := metav1.Condition{Type: "tested", Status: metav1.ConditionTrue, Reason: "testsSucceeded", Message: fmt.Sprintf("tests of revision %v succeeded", status.Revision)}
+
+	if status.Phase != "succeeded" {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "testsFailed"
+		condition.Message = fmt.Sprintf("tests of revision %v failed", status.Revision)
+
+		if instance.Spec.Tests.RollbackOnFailure {
+			rolledBack, err := helmRelease.RollbackFailedTests()
+
+			if err != nil {
+				condition.Message = fmt.Sprintf("%v; rollback failed: %v", condition.Message, err.Error())
+			}
+
+			if rolledBack {
+				status.RolledBackGeneration = instance.Generation
+				condition.Message = fmt.Sprintf("%v; rolled back as revision %v", condition.Message, helmRelease.Revision)
+				instance.Status.History = helmRelease.History
+				instance.Status.Revision = &helmRelease.Revision
+			}
+		}
+	}
+
+	instance.Status.Tests = &status
+	meta.SetStatusCondition(&instance.Status.Conditions, condition)
+
+	return false, r.Status().Update(ctx, instance)
+}
+
+// syncSummary updates the summary of the deployed revision and stores notes and resources in a configmap if they are too large for the status
+func (r *ReleaseReconciler) syncSummary(ctx context.Context, instance *helmv1alpha1.Release, helmRelease *release.Release) error {
+	summary, err := helmRelease.GetSummary()
+
+	if err != nil {
+		return err
+	}
+
+	configmap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "helm-summary-" + instance.GetName(),
+			Namespace: instance.GetNamespace(),
+		},
+	}
+
+	if summary.Data != nil {
+		if _, err := controllerutil.CreateOrUpdate(ctx, r.WithWatch, configmap, func() error {
+			configmap.Data = summary.Data
+			return controllerutil.SetControllerReference(instance, configmap, r.Scheme)
+		}); err != nil {
+			return err
+		}
+
+		summary.ConfigMap = configmap.GetName()
+	} else if instance.Status.Summary != nil && instance.Status.Summary.ConfigMap != "" {
+		if err := r.Delete(ctx, configmap); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	if equality.Semantic.DeepEqual(instance.Status.Summary, &summary.ReleaseSummary) {
+		return nil
+	}
+
+	instance.Status.Summary = &summary.ReleaseSummary
+	return r.Status().Update(ctx, instance)
+}
+
+// syncHealth assesses the resources of the deployed revision and updates the healthy condition
+func (r *ReleaseReconciler) syncHealth(ctx context.Context, instance *helmv1alpha1.Release, helmRelease *release.Release) (bool, error) {
+	rules := []helmv1alpha1.HealthRule{}
+
+	if instance.Spec.Health != nil {
+		rules = instance.Spec.Health.Rules
+	}
+
+	health, err := helmRelease.AssessHealth(rules)
+
+	if err != nil {
+		return false, err
+	}
+
+	unhealthy := []string{}
+
+	for _, resource := range health {
+		if !resource.Healthy {
+			unhealthy = append(unhealthy, fmt.Sprintf("%v/%v: %v", resource.Kind, resource.Name, resource.Message))
+		}
+	}
+
+	condition := metav1.Condition{Type: "healthy", Status: metav1.ConditionTrue, Reason: "healthy", Message: fmt.Sprintf("%v resources healthy", len(health))}
+
+	if len(unhealthy) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "unhealthy"
+
+		// all resources are listed in the status
+		listed := unhealthy
+
+		if len(listed) > 5 {
+			listed = append(listed[:5:5], "...")
+		}
+
+		condition.Message = fmt.Sprintf("%v of %v resources unhealthy: %v", len(unhealthy), len(health), strings.Join(listed, ", "))
+	}
+
+	if len(health) == 0 {
+		health = nil
+	}
+
+	changed := meta.SetStatusCondition(&instance.Status.Conditions, condition)
+
+	if !changed && reflect.DeepEqual(health, instance.Status.Health) {
+		return len(unhealthy) == 0, nil
+	}
+
+	instance.Status.Health = health
+	return len(unhealthy) == 0, r.Status().Update(ctx, instance)
+}
+
+func getHealthInterval(instance *helmv1alpha1.Release) time.Duration {
+
+	if instance.Spec.Health != nil && instance.Spec.Health.Interval > 0 {
+		return time.Duration(instance.Spec.Health.Interval) * time.Second
+	}
+
+	return 30 * time.Second
+}
+
+// shouldRunTests returns whether tests are enabled and the deployed revision is untested or a run was requested by the annotation
+func shouldRunTests(instance *helmv1alpha1.Release, revision int) bool {
+
+	if instance.Spec.Tests == nil || !instance.Spec.Tests.Enabled {
+		return false
+	}
+
+	status := instance.Status.Tests
+	trigger := instance.GetAnnotations()[testAnnotation]
+
+	if trigger != "" && (status == nil || status.Trigger != trigger) {
+		return true
+	}
+
+	return instance.Spec.Tests.AfterDeploy && (status == nil || status.Revision != revision)
+}
+
+func getDriftInterval(instance *helmv1alpha1.Release) time.Duration {
+
+	if instance.Spec.Drift.Interval > 0 {
+		return time.Duration(instance.Spec.Drift.Interval) * time.Second
+	}
+
+	return 300 * time.Second
+}
+
+// handleBlockedDeletion handles a deleted release which can't be uninstalled because its cluster is unreachable or it can't be initialized.
+// The uninstall is retried until the give up time of the uninstall policy has passed. Then the finalizer is removed and the helm release is left behind.
+func (r *ReleaseReconciler) handleBlockedDeletion(ctx context.Context, instance *helmv1alpha1.Release, config *helmv1alpha1.Config, cause error) (ctrl.Result, error) {
+
+	if !controllerutil.ContainsFinalizer(instance, "finalizer.releases.yaho.soer3n.dev") {
+		return ctrl.Result{}, nil
+	}
+
+	policy := instance.Spec.Uninstall
+
+	if policy == nil && config != nil {
+		policy = config.Spec.Uninstall
+	}
+
+	giveUpAfter := release.GetUninstallGiveUpAfter(policy)
+	orphan := policy != nil && policy.Policy == release.UninstallOrphan
+
+	if !orphan && time.Since(instance.GetDeletionTimestamp().Time) < giveUpAfter {
+		revision := 0
+
+		if instance.Status.Revision != nil {
+			revision = *instance.Status.Revision
+		}
+
+		if err := r.syncStatus(ctx, instance, metav1.ConditionFalse, "uninstallFailed", cause.Error(), "uninstallFailed", false, revision, instance.Status.ResolvedVersion); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+
+	r.Log.Info("giving up uninstall of release", "release", instance.GetName(), "giveUpAfter", giveUpAfter, "error", cause.Error())
+	controllerutil.RemoveFinalizer(instance, "finalizer.releases.yaho.soer3n.dev")
+	return ctrl.Result{}, r.Update(ctx, instance)
+}
+
 func (r *ReleaseReconciler) handleFinalizer(helmRelease *release.Release, instance *helmv1alpha1.Release, isRepoMarkedToBeDeleted bool) (bool, error) {
 
 	if isRepoMarkedToBeDeleted {
@@ -228,7 +964,7 @@ func (r *ReleaseReconciler) handleFinalizer(helmRelease *release.Release, instan
 	return false, nil
 }
 
-func (r *ReleaseReconciler) syncStatus(ctx context.Context, instance *helmv1alpha1.Release, stats metav1.ConditionStatus, reason, message, status string, synced bool, revision int) error {
+func (r *ReleaseReconciler) syncStatus(ctx context.Context, instance *helmv1alpha1.Release, stats metav1.ConditionStatus, reason, message, status string, synced bool, revision int, resolvedVersion string) error {
 
 	r.Log.Info("sync status", "release", instance.GetName())
 	instanceLabels := instance.GetLabels()
@@ -252,15 +988,17 @@ func (r *ReleaseReconciler) syncStatus(ctx context.Context, instance *helmv1alph
 	instance.Status.Synced = &synced
 
 	c := meta.FindStatusCondition(instance.Status.Conditions, "synced")
-	if c != nil && c.Message == message && c.Status == stats {
-		if *instance.Status.Revision == revision {
+	if c != nil && c.Message == message && c.Status == stats && c.ObservedGeneration == instance.Generation {
+		if *instance.Status.Revision == revision && instance.Status.RequestedVersion == instance.Spec.Version && instance.Status.ResolvedVersion == resolvedVersion {
 			r.Log.Info("status resource is already up to date.")
 			return nil
 		}
 	}
 
 	instance.Status.Revision = &revision
-	condition := metav1.Condition{Type: "synced", Status: stats, LastTransitionTime: metav1.Time{Time: time.Now()}, Reason: reason, Message: message}
+	instance.Status.RequestedVersion = instance.Spec.Version
+	instance.Status.ResolvedVersion = resolvedVersion
+	condition := metav1.Condition{Type: "synced", Status: stats, ObservedGeneration: instance.Generation, LastTransitionTime: metav1.Time{Time: time.Now()}, Reason: reason, Message: message}
 	meta.SetStatusCondition(&instance.Status.Conditions, condition)
 
 	r.Log.Info("updated labels", "value", instanceLabels)
@@ -306,11 +1044,127 @@ func (r *ReleaseReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	}
 
 	lsPredicate, _ := predicate.LabelSelectorPredicate(selector)
-	pred := predicate.Or(predicate.GenerationChangedPredicate{}, lsPredicate)
+	// an approval of a plan only changes the annotations
+	pred := predicate.Or(predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{}, lsPredicate)
+
+	indexPredicate, _ := predicate.LabelSelectorPredicate(metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			"yaho.soer3n.dev/type": "index",
+		},
+	})
 
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&helmv1alpha1.Release{}).
-		WithEventFilter(pred).
+		For(&helmv1alpha1.Release{}, builder.WithPredicates(pred)).
+		Watches(&v1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.findReleasesForIndex), builder.WithPredicates(indexPredicate)).
+		Watches(&helmv1alpha1.Release{}, handler.EnqueueRequestsFromMapFunc(r.findRelatedReleases), builder.WithPredicates(readinessChangedPredicate())).
+		Watches(&helmv1alpha1.ReleaseGroup{}, handler.EnqueueRequestsFromMapFunc(r.findReleasesForGroup), builder.WithPredicates(predicate.GenerationChangedPredicate{})).
 		WithOptions(controller.Options{MaxConcurrentReconciles: 2}).
 		Complete(r)
 }
+
+// readinessChangedPredicate filters updates of releases which don't change their readiness or deletion for related releases
+func readinessChangedPredicate() predicate.Funcs {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldRelease, ok := e.ObjectOld.(*helmv1alpha1.Release)
+
+			if !ok {
+				return false
+			}
+
+			newRelease, ok := e.ObjectNew.(*helmv1alpha1.Release)
+
+			if !ok {
+				return false
+			}
+
+			oldReady, _ := release.IsReleaseReady(oldRelease)
+			newReady, _ := release.IsReleaseReady(newRelease)
+
+			return oldReady != newReady || (oldRelease.GetDeletionTimestamp() == nil) != (newRelease.GetDeletionTimestamp() == nil)
+		},
+	}
+}
+
+// findReleasesForIndex returns releases with an upgrade policy which are affected by a changed chart index
+func (r *ReleaseReconciler) findReleasesForIndex(ctx context.Context, obj client.Object) []reconcile.Request {
+	requests := []reconcile.Request{}
+	releases := &helmv1alpha1.ReleaseList{}
+	objLabels := obj.GetLabels()
+
+	if err := r.List(ctx, releases, client.InNamespace(obj.GetNamespace())); err != nil {
+		r.Log.Info("error on listing releases for chart index", "error", err.Error())
+		return requests
+	}
+
+	for _, item := range releases.Items {
+		if item.Spec.Upgrade == nil || item.Spec.Repo != objLabels["yaho.soer3n.dev/repo"] || item.Spec.Chart != objLabels["yaho.soer3n.dev/chart"] {
+			continue
+		}
+
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: item.Name, Namespace: item.Namespace},
+		})
+	}
+
+	return requests
+}
+
+// findRelatedReleases returns the releases depending on a changed release and its dependencies if it is deleted
+func (r *ReleaseReconciler) findRelatedReleases(ctx context.Context, obj client.Object) []reconcile.Request {
+	requests := []reconcile.Request{}
+	changed, ok := obj.(*helmv1alpha1.Release)
+
+	if !ok {
+		return requests
+	}
+
+	if changed.GetDeletionTimestamp() != nil {
+		for _, dependency := range changed.Spec.DependsOn {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: release.GetDependencyKey(changed, dependency),
+			})
+		}
+	}
+
+	releases := &helmv1alpha1.ReleaseList{}
+
+	if err := r.List(ctx, releases, client.InNamespace(r.WatchNamespace)); err != nil {
+		r.Log.Info("error on listing releases for dependencies", "error", err.Error())
+		return requests
+	}
+
+	for _, dependent := range release.GetDependents(changed, releases.Items) {
+		namespace, name, _ := strings.Cut(dependent, "/")
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: namespace, Name: name},
+		})
+	}
+
+	return requests
+}
+
+// findReleasesForGroup returns the releases of a changed release group for suspending or resuming them
+func (r *ReleaseReconciler) findReleasesForGroup(ctx context.Context, obj client.Object) []reconcile.Request {
+	requests := []reconcile.Request{}
+	group, ok := obj.(*helmv1alpha1.ReleaseGroup)
+
+	if !ok {
+		return requests
+	}
+
+	releases := &helmv1alpha1.ReleaseList{}
+
+	if err := r.List(ctx, releases, client.InNamespace(group.Namespace), client.MatchingLabels{"releaseGroup": group.Spec.LabelSelector}); err != nil {
+		r.Log.Info("error on listing releases of group", "error", err.Error())
+		return requests
+	}
+
+	for _, item := range releases.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: item.Name, Namespace: item.Namespace},
+		})
+	}
+
+	return requests
+}
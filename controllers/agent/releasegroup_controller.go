@@ -97,6 +97,17 @@ func (r *ReleaseGroupReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return ctrl.Result{}, nil
 	}
 
+	if utils.SetSuspendedCondition(&instance.Status.Conditions, instance.Spec.Suspend, "release group is suspended") {
+		if err := r.Status().Update(ctx, instance); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if instance.Spec.Suspend {
+		reqLogger.Info("reconciliation of release group is suspended")
+		return ctrl.Result{}, nil
+	}
+
 	// fetch owned repos
 	releases := &helmv1alpha1.ReleaseList{}
 	requirement, _ := labels.ParseToRequirements("releaseGroup=" + instance.Spec.LabelSelector)
@@ -96,6 +96,19 @@ func (r *ValuesReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 			}, current)
 
 			if err == nil {
+				// suspended releases pick up the changed values after they are resumed
+				suspended, _, err := isReleaseSuspended(ctx, r.Client, current)
+
+				if err != nil {
+					reqLogger.Info(err.Error())
+					continue
+				}
+
+				if suspended {
+					reqLogger.Info("Skip suspended release.", "release", release)
+					continue
+				}
+
 				if meta.IsStatusConditionTrue(current.Status.Conditions, "synced") {
 
 					condition := metav1.Condition{Type: "synced", Status: metav1.ConditionFalse, LastTransitionTime: metav1.Time{Time: time.Now()}, Reason: "valueschange", Message: "valuesupdated"}
@@ -19,20 +19,30 @@ package helm
 
 import (
 	"context"
+	goerrors "errors"
 	"net/http"
+	"reflect"
 	"time"
 
 	"github.com/go-logr/logr"
 	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
 	"github.com/soer3n/yaho/internal/chart"
+	"github.com/soer3n/yaho/internal/chartversion"
 	"github.com/soer3n/yaho/internal/utils"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	meta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 // ChartReconciler reconciles a Chart object
@@ -46,7 +56,8 @@ type ChartReconciler struct {
 
 // +kubebuilder:rbac:groups=yaho.soer3n.dev,resources="repositories",verbs=get;list;watch
 // +kubebuilder:rbac:groups=yaho.soer3n.dev,resources=charts,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;delete
 // +kubebuilder:rbac:groups=yaho.soer3n.dev,resources=charts/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=yaho.soer3n.dev,resources=charts/finalizers,verbs=update
 
@@ -82,6 +93,22 @@ func (r *ChartReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 		return ctrl.Result{}, err
 	}
 
+	// deletions are handled while the chart is suspended
+	suspended := instance.Spec.Suspend && instance.GetDeletionTimestamp() == nil
+
+	if utils.SetSuspendedCondition(&instance.Status.Conditions, suspended, "chart is suspended") {
+		if err := r.Status().Update(ctx, instance); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if suspended {
+		reqLogger.Info("sync of chart is suspended", "chart", instance.Spec.Name)
+		return ctrl.Result{}, nil
+	}
+
+	resolved := instance.Status.ResolvedVersions
+
 	// set intial status
 	versions := "notSynced"
 	deps := "notSynced"
@@ -127,21 +154,29 @@ func (r *ChartReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 
 	if err != nil {
 		reqLogger.Info("failed to initialize chart resource struct", "name", instance.ObjectMeta.Name)
-		return r.syncStatus(ctx, instance, metav1.ConditionTrue, "initChartFailed", err.Error())
+		return r.syncStatus(ctx, instance, metav1.ConditionTrue, "initChartFailed", err.Error(), false)
 	}
 
 	if err := hc.Update(instance); err != nil {
 		reqLogger.Info("failed to updatechart resource", "name", instance.ObjectMeta.Name)
-		return r.syncStatus(ctx, instance, metav1.ConditionTrue, "createConfigmapsFailed", err.Error())
+
+		if goerrors.Is(err, chartversion.ErrVerificationFailed) {
+			changed := r.setVerifiedCondition(ctx, instance, err)
+			return r.syncStatus(ctx, instance, metav1.ConditionTrue, "verificationFailed", err.Error(), changed)
+		}
+
+		return r.syncStatus(ctx, instance, metav1.ConditionTrue, "createConfigmapsFailed", err.Error(), false)
 	}
 
+	verifiedChanged := r.setVerifiedCondition(ctx, instance, nil)
+
 	versions = "synced"
 	instance.Status.Versions = &versions
 
 	if instance.Spec.CreateDeps {
 		if err := hc.CreateOrUpdateSubCharts(); err != nil {
 			reqLogger.Info("error on managing subcharts. Reconciling.", "name", instance.ObjectMeta.Name, "error", err.Error())
-			return r.syncStatus(ctx, instance, metav1.ConditionTrue, "createDepsFailed", err.Error())
+			return r.syncStatus(ctx, instance, metav1.ConditionTrue, "createDepsFailed", err.Error(), false)
 
 		}
 
@@ -151,12 +186,37 @@ func (r *ChartReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 
 	reqLogger.Info("chart up to date", "name", instance.ObjectMeta.Name)
 
-	return r.syncStatus(ctx, instance, metav1.ConditionTrue, "success", "all up to date")
+	// versions resolved from constraints can change after each index refresh
+	versionsChanged := !reflect.DeepEqual(resolved, instance.Status.ResolvedVersions)
+
+	return r.syncStatus(ctx, instance, metav1.ConditionTrue, "success", "all up to date", versionsChanged || verifiedChanged)
+}
+
+// setVerifiedCondition sets the result of the provenance verification if it is enabled for the chart or its repository
+func (r *ChartReconciler) setVerifiedCondition(ctx context.Context, instance *helmv1alpha1.Chart, err error) bool {
+	condition := metav1.Condition{Type: "verified", Status: metav1.ConditionTrue, Reason: "verified", Message: "chart archives match their provenance files"}
+
+	if err != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "verificationFailed"
+		condition.Message = err.Error()
+		return meta.SetStatusCondition(&instance.Status.Conditions, condition)
+	}
+
+	repository := &helmv1alpha1.Repository{}
+
+	if instance.Spec.Verify == nil {
+		if getErr := r.Get(ctx, types.NamespacedName{Name: instance.Spec.Repository}, repository); getErr != nil || repository.Spec.Verify == nil {
+			return meta.RemoveStatusCondition(&instance.Status.Conditions, "verified")
+		}
+	}
+
+	return meta.SetStatusCondition(&instance.Status.Conditions, condition)
 }
 
-func (r *ChartReconciler) syncStatus(ctx context.Context, instance *helmv1alpha1.Chart, stats metav1.ConditionStatus, reason, message string) (ctrl.Result, error) {
+func (r *ChartReconciler) syncStatus(ctx context.Context, instance *helmv1alpha1.Chart, stats metav1.ConditionStatus, reason, message string, changed bool) (ctrl.Result, error) {
 	c := meta.FindStatusCondition(instance.Status.Conditions, "synced")
-	if c != nil && c.Message == message && c.Status == stats {
+	if c != nil && c.Message == message && c.Status == stats && !changed {
 		return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
 	}
 
@@ -174,7 +234,41 @@ func (r *ChartReconciler) syncStatus(ctx context.Context, instance *helmv1alpha1
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *ChartReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	indexPredicate, _ := predicate.LabelSelectorPredicate(metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			LabelPrefix + "type": "index",
+		},
+	})
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&helmv1alpha1.Chart{}).
+		Watches(&v1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.findChartsForIndex), builder.WithPredicates(indexPredicate)).
 		Complete(r)
 }
+
+// findChartsForIndex returns the charts which have to resolve their versions again after an index refresh
+func (r *ChartReconciler) findChartsForIndex(ctx context.Context, obj client.Object) []reconcile.Request {
+	requests := []reconcile.Request{}
+	charts := &helmv1alpha1.ChartList{}
+	objLabels := obj.GetLabels()
+
+	opts := &client.ListOptions{
+		LabelSelector: labels.SelectorFromSet(map[string]string{
+			LabelPrefix + "repo":  objLabels[LabelPrefix+"repo"],
+			LabelPrefix + "chart": objLabels[LabelPrefix+"chart"],
+		}),
+	}
+
+	if err := r.List(ctx, charts, opts); err != nil {
+		r.Log.Info("error on listing charts for chart index", "error", err.Error())
+		return requests
+	}
+
+	for _, item := range charts.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: item.Name},
+		})
+	}
+
+	return requests
+}
@@ -44,6 +44,9 @@ var nopLogger = func(_ string, _ ...interface{}) {}
 
 const LabelPrefix = "yaho.soer3n.dev/"
 
+// defaultResyncInterval is the interval between two syncs of repositories without enabled sync
+const defaultResyncInterval = time.Hour
+
 // RepoReconciler reconciles a Repo object
 type RepoReconciler struct {
 	client.Client
@@ -55,7 +58,8 @@ type RepoReconciler struct {
 
 // +kubebuilder:rbac:groups=yaho.soer3n.dev,resources="repositories",verbs=get;list;watch;update
 // +kubebuilder:rbac:groups=yaho.soer3n.dev,resources="charts",verbs=get;list;watch;create;update
-// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 // +kubebuilder:rbac:groups=yaho.soer3n.dev,resources=repositories/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=yaho.soer3n.dev,resources=repositories/finalizers,verbs=update
 
@@ -90,6 +94,25 @@ func (r *RepoReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 		return ctrl.Result{}, err
 	}
 
+	// deletions are handled while the repository is suspended
+	suspended := instance.Spec.Suspend && instance.GetDeletionTimestamp() == nil
+
+	if utils.SetSuspendedCondition(&instance.Status.Conditions, suspended, "repository is suspended") {
+		if err := r.Status().Update(ctx, instance); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if suspended {
+		reqLogger.Info("sync of repo is suspended", "repo", instance.Spec.Name)
+		return ctrl.Result{}, nil
+	}
+
+	if next, skip := getNextSync(instance); skip {
+		reqLogger.Info("Skip sync of repo.", "repo", instance.Spec.Name, "next", next)
+		return ctrl.Result{RequeueAfter: next}, nil
+	}
+
 	var hc *repository.Repo
 	var requeue bool
 
@@ -138,6 +161,15 @@ func (r *RepoReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 
 	synced = true
 	instance.Status.Synced = &synced
+	instance.Status.LastSyncTime = &metav1.Time{Time: time.Now()}
+	instance.Status.ObservedGeneration = instance.ObjectMeta.Generation
+
+	if !hc.NotModified {
+		instance.Status.Digest = hc.Digest
+		instance.Status.ETag = hc.ETag
+		instance.Status.LastModified = hc.LastModified
+		instance.Status.SkippedCharts = hc.SkippedCharts
+	}
 
 	reqLogger.Info("Repo deployed", "name", instance.Spec.Name, "namespace", instance.ObjectMeta.Namespace)
 	reqLogger.Info("Don't reconcile repos.", "name", instance.Spec.Name)
@@ -172,32 +204,67 @@ func (r *RepoReconciler) syncStatus(ctx context.Context, instance *helmv1alpha1.
 	r.Log.Info("chartlength", "value", newChartCount)
 
 	if err != nil {
+		synced := false
+		instance.Status.Synced = &synced
 		stats = metav1.ConditionFalse
 		message = err.Error()
 	}
 
 	condition := metav1.Condition{Type: "synced", Status: stats, LastTransitionTime: metav1.Time{Time: time.Now()}, Reason: reason, Message: message}
 
-	if !meta.IsStatusConditionPresentAndEqual(instance.Status.Conditions, "synced", stats) || instance.Status.Conditions[0].Message != message {
+	// status is updated after each successful sync for storing sync time and digest
+	if err == nil || !meta.IsStatusConditionPresentAndEqual(instance.Status.Conditions, "synced", stats) || meta.FindStatusCondition(instance.Status.Conditions, "synced").Message != message || instance.Status.Charts == nil || *instance.Status.Charts != newChartCount {
 		meta.SetStatusCondition(&instance.Status.Conditions, condition)
 		instance.Status.Charts = &newChartCount
-		_ = r.Status().Update(ctx, instance)
-		return ctrl.Result{}, nil
-	}
 
-	if *instance.Status.Charts != newChartCount {
-		instance.Status.Charts = &newChartCount
-		_ = r.Status().Update(ctx, instance)
-		return ctrl.Result{}, nil
+		if err := r.Status().Update(ctx, instance); err != nil {
+			r.Log.Info("Error on updating repo status.", "repo", instance.ObjectMeta.Name, "error", err.Error())
+		}
 	}
 
-	if *instance.Status.Synced {
-		r.Log.Info("Reconcile repo after status sync regular in 10 seconds.", "repo", instance.ObjectMeta.Name)
+	if err != nil {
+		r.Log.Info("Reconcile unsynced repo in 10 seconds.", "repo", instance.ObjectMeta.Name)
 		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
 	}
 
-	r.Log.Info("Reconcile unsynced repo in 10 seconds.", "repo", instance.ObjectMeta.Name)
-	return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	interval := getSyncInterval(instance)
+	r.Log.Info("Reconcile repo after sync interval.", "repo", instance.ObjectMeta.Name, "interval", interval)
+	return ctrl.Result{RequeueAfter: interval}, nil
+}
+
+// getNextSync returns whether the sync can be skipped and the duration until the next one is due
+func getNextSync(instance *helmv1alpha1.Repository) (time.Duration, bool) {
+
+	if instance.Status.Synced == nil || !*instance.Status.Synced || instance.Status.LastSyncTime == nil {
+		return 0, false
+	}
+
+	if instance.Status.ObservedGeneration != instance.ObjectMeta.Generation || instance.GetDeletionTimestamp() != nil {
+		return 0, false
+	}
+
+	next := time.Until(instance.Status.LastSyncTime.Add(getSyncInterval(instance)))
+
+	if next <= 0 {
+		return 0, false
+	}
+
+	return next, true
+}
+
+// getSyncInterval returns the configured interval of an enabled sync, otherwise the default resync interval
+func getSyncInterval(instance *helmv1alpha1.Repository) time.Duration {
+
+	// repositories without periodic sync are still resynced from time to time so that lost or outdated resources are restored
+	if !instance.Spec.Sync.Enabled {
+		return defaultResyncInterval
+	}
+
+	if instance.Spec.Sync.Interval > 0 {
+		return time.Duration(instance.Spec.Sync.Interval) * time.Second
+	}
+
+	return 10 * time.Second
 }
 
 func (r *RepoReconciler) handleFinalizer(hc *repository.Repo, instance *helmv1alpha1.Repository) (bool, error) {
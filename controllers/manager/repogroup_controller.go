@@ -111,6 +111,11 @@ func (r *RepoGroupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 	go func() {
 		for _, repository := range spec {
+			// repositories without own filter inherit the filter of the group
+			if repository.Filter == nil {
+				repository.Filter = instance.Spec.Filter
+			}
+
 			create <- helmv1alpha1.Repository{
 				ObjectMeta: metav1.ObjectMeta{
 					Name: repository.Name,
@@ -79,6 +79,8 @@ func (c *Chart) setVersions(instance *helmv1alpha1.Chart, namespace string, sche
 
 	var chartVersions ChartVersions
 
+	resolved := map[string]string{}
+
 	for _, version := range instance.Spec.Versions {
 		c.logger.Info("init version struct", "version", version)
 		obj, err := chartversion.New(version, namespace, instance, nil, c.index, scheme, c.logger, c.K8sClient, c.getter)
@@ -100,9 +102,11 @@ func (c *Chart) setVersions(instance *helmv1alpha1.Chart, namespace string, sche
 			instance.Status.Tags = &obj.Version.Tags
 		}
 
+		resolved[version] = obj.Version.Version
 		chartVersions = append(chartVersions, obj)
 	}
 
+	instance.Status.ResolvedVersions = resolved
 	c.Versions = chartVersions
 	return nil
 }
@@ -1,14 +1,20 @@
 package chartversion
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 
 	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
+	"github.com/soer3n/yaho/internal/utils"
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/registry"
 	"helm.sh/helm/v3/pkg/repo"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/labels"
@@ -64,6 +70,22 @@ func (chartVersion *ChartVersion) loadChartByURL(releaseClient *action.Install)
 		credentials = chartVersion.getCredentials()
 	}
 
+	if chartVersion.repo.Spec.TLSSecret != "" {
+		ca, cert, key, err := utils.GetTLSSecretData(chartVersion.k8sClient, chartVersion.repo.Spec.TLSSecret, chartVersion.namespace)
+
+		if err != nil {
+			return err
+		}
+
+		if credentials == nil {
+			credentials = &Auth{}
+		}
+
+		credentials.Ca = ca
+		credentials.Cert = cert
+		credentials.Key = key
+	}
+
 	if err := chartVersion.downloadChart(credentials); err != nil {
 		return err
 	}
@@ -75,6 +97,12 @@ func (chartVersion *ChartVersion) setChartURL(index repo.ChartVersions) error {
 
 	for _, e := range index {
 		if e.Version == chartVersion.Version.Version {
+			// charts of git repositories are referenced by their path in the repository
+			if chartVersion.repo.Spec.Git != nil {
+				chartVersion.url = chartVersion.Version.URLs[0]
+				return nil
+			}
+
 			// use first url because it should be set in each case
 			chartURL, err := repo.ResolveReferenceURL(chartVersion.repo.Spec.URL, chartVersion.Version.URLs[0])
 
@@ -124,27 +152,47 @@ func (chartVersion *ChartVersion) loadChartByResources(helmChart *chart.Chart, a
 }
 
 func (chartVersion *ChartVersion) downloadChart(opts *Auth) error {
-	var resp *http.Response
-	var err error
 
-	req, err := http.NewRequest(http.MethodGet, chartVersion.url, nil)
+	if chartVersion.repo.Spec.Git != nil {
+		if chartVersion.getVerification() != nil {
+			return fmt.Errorf("%w: provenance files are not supported for git repositories", ErrVerificationFailed)
+		}
+
+		return chartVersion.checkoutChart(opts)
+	}
+
+	tlsConfig, err := chartVersion.getTLSConfig(opts)
+
 	if err != nil {
 		chartVersion.logger.Info(err.Error())
 		return err
 	}
 
-	if opts != nil {
-		if opts.User != "" && opts.Password != "" {
-			req.SetBasicAuth(opts.User, opts.Password)
-		}
+	if registry.IsOCI(chartVersion.url) {
+		return chartVersion.pullChart(opts, tlsConfig)
 	}
 
-	if resp, err = chartVersion.getter.Do(req); err != nil {
+	raw, err := chartVersion.fetch(chartVersion.url, opts, tlsConfig)
+
+	if err != nil {
 		chartVersion.logger.Info(err.Error())
 		return err
 	}
 
-	chart, err := loader.LoadArchive(resp.Body)
+	if chartVersion.getVerification() != nil {
+		prov, err := chartVersion.fetch(chartVersion.url+".prov", opts, tlsConfig)
+
+		if err != nil {
+			chartVersion.logger.Info("could not download provenance file", "error", err.Error())
+		}
+
+		if err := chartVersion.verifyArchive(raw, prov); err != nil {
+			chartVersion.logger.Info(err.Error())
+			return err
+		}
+	}
+
+	chart, err := loader.LoadArchive(bytes.NewReader(raw))
 
 	if err != nil {
 		chartVersion.logger.Info(err.Error())
@@ -152,5 +200,51 @@ func (chartVersion *ChartVersion) downloadChart(opts *Auth) error {
 	}
 
 	chartVersion.Obj = chart
+	chartVersion.archive = raw
 	return nil
 }
+
+// fetch returns the body of a request to the url with the credentials of the repository
+func (chartVersion *ChartVersion) fetch(url string, opts *Auth, tlsConfig *tls.Config) ([]byte, error) {
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if opts != nil {
+		// credentials are only passed to other hosts than the repository if explicitly requested
+		if chartVersion.repo.Spec.PassCredentialsAll || utils.IsSameHost(url, chartVersion.repo.Spec.URL) {
+			utils.SetRequestAuth(req, opts.User, opts.Password, opts.Token, opts.Headers)
+		} else {
+			chartVersion.logger.Info("credentials are not passed to chart url on another host", "url", url)
+		}
+	}
+
+	resp, err := utils.NewTLSHTTPClient(chartVersion.getter, tlsConfig).Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("unexpected status %v on downloading %v", resp.Status, url)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+func (chartVersion *ChartVersion) getTLSConfig(opts *Auth) (*tls.Config, error) {
+	var ca, cert, key string
+
+	if opts != nil {
+		ca = opts.Ca
+		cert = opts.Cert
+		key = opts.Key
+	}
+
+	return utils.NewTLSConfig(ca, cert, key, chartVersion.repo.Spec.InsecureSkipTLSVerify)
+}
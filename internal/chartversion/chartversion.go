@@ -4,6 +4,7 @@ import (
 	"context"
 	b64 "encoding/base64"
 	"errors"
+	"strings"
 	"sync"
 	"time"
 
@@ -34,6 +35,8 @@ const configMapRepoGroupLabelKey = "yaho.soer3n.dev/repoGroup"
 const configMapLabelType = "yaho.soer3n.dev/type"
 const configMapLabelSubName = "yaho.soer3n.dev/subname"
 const configMapLabelUnmanaged = "yaho.soer3n.dev/unmanaged"
+const configMapLabelChunk = "yaho.soer3n.dev/chunk"
+const configMapAnnotationChunks = "yaho.soer3n.dev/chunks"
 
 func New(version, namespace string, chartObj *helmv1alpha1.Chart, vals chartutil.Values, index repo.ChartVersions, scheme *runtime.Scheme, logger logr.Logger, k8sclient client.WithWatch, g utils.HTTPClientInterface) (*ChartVersion, error) {
 
@@ -75,6 +78,7 @@ func New(version, namespace string, chartObj *helmv1alpha1.Chart, vals chartutil
 	}
 
 	obj.repo = repo
+	obj.setStorage()
 
 	if err := obj.setChartURL(index); err != nil {
 		return obj, err
@@ -83,7 +87,7 @@ func New(version, namespace string, chartObj *helmv1alpha1.Chart, vals chartutil
 	options := &action.ChartPathOptions{
 		Version:               version,
 		InsecureSkipTLSverify: false,
-		Verify:                false,
+		Verify:                obj.getVerification() != nil,
 	}
 
 	if vals == nil {
@@ -246,8 +250,16 @@ func (chartVersion *ChartVersion) getCredentials() *Auth {
 
 	username, _ := b64.StdEncoding.DecodeString(string(secretObj.Data["user"]))
 	pw, _ := b64.StdEncoding.DecodeString(string(secretObj.Data["password"]))
+	token, _ := b64.StdEncoding.DecodeString(string(secretObj.Data["token"]))
+	sshKey, _ := b64.StdEncoding.DecodeString(string(secretObj.Data["sshKey"]))
+	knownHosts, _ := b64.StdEncoding.DecodeString(string(secretObj.Data["knownHosts"]))
+	headers, _ := b64.StdEncoding.DecodeString(string(secretObj.Data["headers"]))
 	creds.User = string(username)
 	creds.Password = string(pw)
+	creds.Token = strings.TrimSuffix(string(token), "\n")
+	creds.SSHKey = string(sshKey)
+	creds.KnownHosts = string(knownHosts)
+	creds.Headers = utils.ParseAuthHeaders(string(headers))
 
 	return creds
 }
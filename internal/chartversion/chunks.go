@@ -0,0 +1,282 @@
+package chartversion
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	"helm.sh/helm/v3/pkg/chart"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// chunkSize keeps each chunk including metadata below the 1 MiB limit of configmaps and secrets
+const chunkSize = 768 * 1024
+
+const chunkDataKey = "chunk"
+
+// chunkStorage stores the files as gzipped tar archive split into numbered configmaps or secrets
+type chunkStorage struct {
+	storageBase
+	secret bool
+}
+
+func (s *chunkStorage) save(fileType string, files []*chart.File) error {
+
+	raw, err := packFiles(files)
+
+	if err != nil {
+		return err
+	}
+
+	chunks := splitChunks(raw, chunkSize)
+
+	for i, data := range chunks {
+		meta := metav1.ObjectMeta{
+			Name:      s.objectName(fileType, strconv.Itoa(i)),
+			Namespace: s.chartVersion.namespace,
+			Labels:    s.objectLabels(fileType),
+			// each chunk records the total count so that missing chunks are detected on loading
+			Annotations: map[string]string{configMapAnnotationChunks: strconv.Itoa(len(chunks))},
+		}
+		meta.Labels[configMapLabelChunk] = strconv.Itoa(i)
+
+		var obj client.Object = &v1.ConfigMap{ObjectMeta: meta, BinaryData: map[string][]byte{chunkDataKey: data}}
+
+		if s.secret {
+			obj = &v1.Secret{ObjectMeta: meta, Data: map[string][]byte{chunkDataKey: data}}
+		}
+
+		if err := s.deployObject(obj); err != nil {
+			s.chartVersion.logger.Error(err, "error on creating chunk", "name", meta.Name)
+			return err
+		}
+	}
+
+	return s.cleanup(fileType, len(chunks))
+}
+
+func (s *chunkStorage) load(fileType string) ([]*chart.File, error) {
+
+	chunks, err := s.getChunks(fileType)
+
+	if err != nil || len(chunks) == 0 {
+		return nil, err
+	}
+
+	return unpackFiles(bytes.Join(chunks, nil))
+}
+
+// getChunks returns the data of the chunks ordered by their number
+func (s *chunkStorage) getChunks(fileType string) ([][]byte, error) {
+	chunks := map[int][]byte{}
+	total := 0
+
+	if s.secret {
+		list := &v1.SecretList{}
+
+		if err := s.listObjects(list, fileType, true); err != nil {
+			return nil, err
+		}
+
+		for _, item := range list.Items {
+			i, _ := strconv.Atoi(item.Labels[configMapLabelChunk])
+			chunks[i] = item.Data[chunkDataKey]
+			total = getChunkCount(&item, total)
+		}
+	} else {
+		list := &v1.ConfigMapList{}
+
+		if err := s.listObjects(list, fileType, true); err != nil {
+			return nil, err
+		}
+
+		for _, item := range list.Items {
+			i, _ := strconv.Atoi(item.Labels[configMapLabelChunk])
+			chunks[i] = item.BinaryData[chunkDataKey]
+			total = getChunkCount(&item, total)
+		}
+	}
+
+	if len(chunks) == 0 {
+		return nil, nil
+	}
+
+	return orderChunks(chunks, total)
+}
+
+// getChunkCount returns the highest count of chunks recorded so far
+func getChunkCount(obj client.Object, current int) int {
+
+	if count, err := strconv.Atoi(obj.GetAnnotations()[configMapAnnotationChunks]); err == nil && count > current {
+		return count
+	}
+
+	return current
+}
+
+// cleanup removes chunks of a previous save which are not needed anymore
+func (s *chunkStorage) cleanup(fileType string, count int) error {
+	var objs []client.Object
+
+	if s.secret {
+		list := &v1.SecretList{}
+
+		if err := s.listObjects(list, fileType, true); err != nil {
+			return err
+		}
+
+		for i := range list.Items {
+			objs = append(objs, &list.Items[i])
+		}
+	} else {
+		list := &v1.ConfigMapList{}
+
+		if err := s.listObjects(list, fileType, true); err != nil {
+			return err
+		}
+
+		for i := range list.Items {
+			objs = append(objs, &list.Items[i])
+		}
+	}
+
+	for _, obj := range objs {
+		if i, err := strconv.Atoi(obj.GetLabels()[configMapLabelChunk]); err == nil && i < count {
+			continue
+		}
+
+		if err := s.chartVersion.k8sClient.Delete(context.Background(), obj); err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *chunkStorage) deployObject(obj client.Object) error {
+	defer s.chartVersion.mu.Unlock()
+	s.chartVersion.mu.Lock()
+
+	if err := controllerutil.SetControllerReference(s.chartVersion.owner, obj, s.chartVersion.scheme); err != nil {
+		return err
+	}
+
+	current := obj.DeepCopyObject().(client.Object)
+
+	if err := s.chartVersion.k8sClient.Get(context.Background(), client.ObjectKeyFromObject(obj), current); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return s.chartVersion.k8sClient.Create(context.Background(), obj)
+		}
+		return err
+	}
+
+	obj.SetResourceVersion(current.GetResourceVersion())
+	return s.chartVersion.k8sClient.Update(context.Background(), obj)
+}
+
+// orderChunks returns the chunks sorted by their number and fails if one of the total count is missing.
+// Chunks saved without count are only checked for gaps.
+func orderChunks(chunks map[int][]byte, total int) ([][]byte, error) {
+
+	if total == 0 {
+		for k := range chunks {
+			if k+1 > total {
+				total = k + 1
+			}
+		}
+	}
+
+	ordered := make([][]byte, 0, total)
+
+	for i := 0; i < total; i++ {
+		data, ok := chunks[i]
+
+		if !ok {
+			return nil, k8serrors.NewBadRequest(fmt.Sprintf("chunk %v of %v is missing", i+1, total))
+		}
+
+		ordered = append(ordered, data)
+	}
+
+	return ordered, nil
+}
+
+func splitChunks(raw []byte, size int) [][]byte {
+	chunks := [][]byte{}
+
+	for len(raw) > size {
+		chunks = append(chunks, raw[:size])
+		raw = raw[size:]
+	}
+
+	return append(chunks, raw)
+}
+
+// packFiles returns the files as gzipped tar archive
+func packFiles(files []*chart.File) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(zw)
+
+	for _, f := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: f.Name, Mode: 0644, Size: int64(len(f.Data))}); err != nil {
+			return nil, err
+		}
+
+		if _, err := tw.Write(f.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// unpackFiles returns the files of a gzipped tar archive
+func unpackFiles(raw []byte) ([]*chart.File, error) {
+	var files []*chart.File
+
+	zr, err := gzip.NewReader(bytes.NewReader(raw))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer zr.Close()
+	tr := tar.NewReader(zr)
+
+	for {
+		header, err := tr.Next()
+
+		if err == io.EOF {
+			return files, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := io.ReadAll(tr)
+
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, &chart.File{Name: header.Name, Data: data})
+	}
+}
@@ -19,16 +19,17 @@ import (
 func (chartVersion *ChartVersion) createConfigMaps(cm chan v1.ConfigMap, deps []*chart.Chart) error {
 
 	wg := &sync.WaitGroup{}
+	errs := make(chan error, 2)
 
 	wg.Add(3)
 
 	go func() {
-		chartVersion.createTemplateConfigMap(cm, "tmpl")
+		errs <- chartVersion.storage.save("tmpl", chartVersion.Obj.Templates)
 		wg.Done()
 	}()
 
 	go func() {
-		chartVersion.createTemplateConfigMap(cm, "crds")
+		errs <- chartVersion.storage.save("crds", chartVersion.Obj.CRDs())
 		wg.Done()
 	}()
 
@@ -38,6 +39,14 @@ func (chartVersion *ChartVersion) createConfigMaps(cm chan v1.ConfigMap, deps []
 	}()
 
 	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -98,9 +107,7 @@ func (chartVersion *ChartVersion) deployConfigMap(configmap v1.ConfigMap) error
 	}, current)
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
-			if err = chartVersion.k8sClient.Create(context.TODO(), &configmap); err != nil {
-				return err
-			}
+			return chartVersion.k8sClient.Create(context.TODO(), &configmap)
 		}
 		return err
 	}
@@ -112,31 +119,18 @@ func (chartVersion *ChartVersion) deployConfigMap(configmap v1.ConfigMap) error
 	return nil
 }
 
-func (chartVersion *ChartVersion) createTemplateConfigMap(cm chan v1.ConfigMap, name string) {
+// getTemplateConfigMaps returns a configmap for the files in the base directory and one for each subdirectory
+func (s *configMapStorage) getTemplateConfigMaps(fileType string, list []*chart.File) []v1.ConfigMap {
 	immutable := new(bool)
 	*immutable = true
 
-	var list []*chart.File
-
-	switch name {
-	case "crds":
-		list = chartVersion.Obj.CRDs()
-	default:
-		list = chartVersion.Obj.Templates
-	}
-
-	objectMeta := metav1.ObjectMeta{
-		Name:      "helm-" + name + "-" + chartVersion.repo.Spec.Name + "-" + chartVersion.Version.Metadata.Name + "-" + chartVersion.Version.Metadata.Version,
-		Namespace: chartVersion.namespace,
-		Labels: map[string]string{
-			configMapLabelKey:     chartVersion.Version.Metadata.Name + "-" + chartVersion.Version.Metadata.Version,
-			configMapRepoLabelKey: chartVersion.owner.Spec.Repository,
-			configMapLabelType:    name,
-		},
-	}
 	baseConfigmap := v1.ConfigMap{
-		Immutable:  immutable,
-		ObjectMeta: objectMeta,
+		Immutable: immutable,
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.objectName(fileType),
+			Namespace: s.chartVersion.namespace,
+			Labels:    s.objectLabels(fileType),
+		},
 	}
 
 	configMapMap := make(map[string]v1.ConfigMap)
@@ -162,16 +156,15 @@ func (chartVersion *ChartVersion) createTemplateConfigMap(cm chan v1.ConfigMap,
 
 		fileName := strings.Replace(path[2], "/", "", 1)
 		if _, ok := configMapMap[key]; !ok {
+			objectLabels := s.objectLabels(fileType)
+			objectLabels[configMapLabelSubName] = key
+
 			configMapMap[key] = v1.ConfigMap{
 				Immutable: immutable,
 				ObjectMeta: metav1.ObjectMeta{
-					Name:      "helm-" + name + "-" + chartVersion.repo.Spec.Name + "-" + chartVersion.Version.Metadata.Name + "-" + key + "-" + chartVersion.Version.Metadata.Version,
-					Namespace: chartVersion.namespace,
-					Labels: map[string]string{
-						configMapLabelKey:     chartVersion.Version.Metadata.Name + "-" + chartVersion.Version.Metadata.Version,
-						configMapRepoLabelKey: chartVersion.owner.Spec.Repository,
-						configMapLabelType:    name,
-					},
+					Name:      s.objectName(fileType, key),
+					Namespace: s.chartVersion.namespace,
+					Labels:    objectLabels,
 				},
 				BinaryData: map[string][]byte{
 					fileName: entry.Data,
@@ -184,12 +177,13 @@ func (chartVersion *ChartVersion) createTemplateConfigMap(cm chan v1.ConfigMap,
 	}
 
 	baseConfigmap.BinaryData = binaryData
-	cm <- baseConfigmap
+	configmaps := []v1.ConfigMap{baseConfigmap}
 
 	for _, configmap := range configMapMap {
-		cm <- configmap
+		configmaps = append(configmaps, configmap)
 	}
 
+	return configmaps
 }
 
 func (chartVersion *ChartVersion) createDefaultValueConfigMap(cm chan v1.ConfigMap, values map[string]interface{}) {
@@ -0,0 +1,81 @@
+package chartversion
+
+import (
+	"os"
+	"path/filepath"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// fileStorage caches the chart archive in a directory which can be a mounted persistent volume
+type fileStorage struct {
+	storageBase
+	path string
+}
+
+func (s *fileStorage) save(fileType string, files []*chart.File) error {
+
+	archive := s.archivePath()
+
+	if _, err := os.Stat(archive); err == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(archive), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.MkdirTemp(filepath.Dir(archive), ".tmp-")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.RemoveAll(tmp)
+
+	// keep the downloaded archive if present or package the loaded chart otherwise
+	name := filepath.Join(tmp, filepath.Base(archive))
+
+	if s.chartVersion.archive != nil {
+		err = os.WriteFile(name, s.chartVersion.archive, 0644)
+	} else {
+		name, err = chartutil.Save(s.chartVersion.Obj, tmp)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(name, archive)
+}
+
+func (s *fileStorage) load(fileType string) ([]*chart.File, error) {
+
+	if _, err := os.Stat(s.archivePath()); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	c, err := loader.Load(s.archivePath())
+
+	if err != nil {
+		return nil, err
+	}
+
+	if fileType == "crds" {
+		return c.CRDs(), nil
+	}
+
+	return c.Templates, nil
+}
+
+func (s *fileStorage) archivePath() string {
+	dir := s.path
+
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "yaho", "charts")
+	}
+
+	return filepath.Join(dir, s.chartVersion.repo.Spec.Name, s.chart+"-"+s.version+".tgz")
+}
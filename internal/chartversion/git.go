@@ -0,0 +1,59 @@
+package chartversion
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/soer3n/yaho/internal/utils"
+	"helm.sh/helm/v3/pkg/chart/loader"
+)
+
+func (chartVersion *ChartVersion) checkoutChart(opts *Auth) error {
+	var auth *utils.GitAuth
+
+	if opts != nil {
+		auth = &utils.GitAuth{
+			User:       opts.User,
+			Token:      opts.Token,
+			SSHKey:     opts.SSHKey,
+			KnownHosts: opts.KnownHosts,
+		}
+	}
+
+	source := chartVersion.repo.Spec.Git
+
+	// the chart is loaded from the commit recorded in the index, so that the content of an indexed version can't change
+	if chartVersion.Version != nil && chartVersion.Version.Metadata != nil && chartVersion.Version.Annotations[utils.GitRevisionAnnotation] != "" {
+		pinned := *source
+		pinned.Commit = chartVersion.Version.Annotations[utils.GitRevisionAnnotation]
+		source = &pinned
+	}
+
+	err := utils.WithGitCheckout(chartVersion.repo.Spec.Name, chartVersion.repo.Spec.URL, source, auth, func(dir, revision string) error {
+		if source.Commit != "" && revision != source.Commit {
+			return fmt.Errorf("checked out revision %v instead of %v", revision, source.Commit)
+		}
+
+		path, err := utils.JoinGitPath(dir, filepath.FromSlash(chartVersion.url))
+
+		if err != nil {
+			return err
+		}
+
+		chart, err := loader.LoadDir(path)
+
+		if err != nil {
+			return err
+		}
+
+		chartVersion.Obj = chart
+		return nil
+	})
+
+	if err != nil {
+		chartVersion.logger.Info(err.Error())
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package chartversion
+
+import (
+	"bytes"
+	"crypto/tls"
+	"strings"
+
+	"github.com/soer3n/yaho/internal/utils"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+func (chartVersion *ChartVersion) pullChart(opts *Auth, tlsConfig *tls.Config) error {
+	var user, password string
+
+	if opts != nil {
+		user = opts.User
+		password = opts.Password
+	}
+
+	c, err := utils.NewRegistryClient(chartVersion.repo.Spec.URL, user, password, chartVersion.repo.Spec.PlainHTTP, tlsConfig)
+
+	if err != nil {
+		chartVersion.logger.Info(err.Error())
+		return err
+	}
+
+	verify := chartVersion.getVerification() != nil
+	res, err := c.Pull(strings.TrimPrefix(chartVersion.url, registry.OCIScheme+"://"), registry.PullOptWithProv(verify), registry.PullOptIgnoreMissingProv(true))
+
+	if err != nil {
+		chartVersion.logger.Info(err.Error())
+		return err
+	}
+
+	if verify {
+		var prov []byte
+
+		if res.Prov != nil {
+			prov = res.Prov.Data
+		}
+
+		if err := chartVersion.verifyArchive(res.Chart.Data, prov); err != nil {
+			chartVersion.logger.Info(err.Error())
+			return err
+		}
+	}
+
+	chart, err := loader.LoadArchive(bytes.NewReader(res.Chart.Data))
+
+	if err != nil {
+		chartVersion.logger.Info(err.Error())
+		return err
+	}
+
+	chartVersion.Obj = chart
+	chartVersion.archive = res.Chart.Data
+	return nil
+}
@@ -0,0 +1,169 @@
+package chartversion
+
+import (
+	"context"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const storageCompressed = "compressed"
+const storageSecret = "secret"
+const storageFilesystem = "filesystem"
+
+// storage represents a backend for templates and crds of a chart version
+type storage interface {
+	// save persists the files of the given type which is either "tmpl" or "crds"
+	save(fileType string, files []*chart.File) error
+	// load returns the persisted files of the given type
+	load(fileType string) ([]*chart.File, error)
+}
+
+// storageBase holds chart name and version as they were resolved from the index
+type storageBase struct {
+	chartVersion *ChartVersion
+	chart        string
+	version      string
+}
+
+// setStorage selects the storage backend configured for the repository of the chart version
+func (chartVersion *ChartVersion) setStorage() {
+	base := storageBase{
+		chartVersion: chartVersion,
+		chart:        chartVersion.Version.Metadata.Name,
+		version:      chartVersion.Version.Metadata.Version,
+	}
+
+	chartVersion.storage = &configMapStorage{storageBase: base}
+
+	if chartVersion.repo.Spec.Storage == nil {
+		return
+	}
+
+	switch chartVersion.repo.Spec.Storage.Type {
+	case storageCompressed:
+		chartVersion.storage = &chunkStorage{storageBase: base}
+	case storageSecret:
+		chartVersion.storage = &chunkStorage{storageBase: base, secret: true}
+	case storageFilesystem:
+		chartVersion.storage = &fileStorage{storageBase: base, path: chartVersion.repo.Spec.Storage.Path}
+	}
+}
+
+// objectName returns the name of a resource of the chart version with optional parts between chart name and version
+func (s storageBase) objectName(fileType string, parts ...string) string {
+	name := append([]string{"helm", fileType, s.chartVersion.repo.Spec.Name, s.chart}, parts...)
+	return strings.Join(append(name, s.version), "-")
+}
+
+// objectLabels returns the labels of resources with files of the chart version
+func (s storageBase) objectLabels(fileType string) map[string]string {
+	return map[string]string{
+		configMapLabelKey:     s.chart + "-" + s.version,
+		configMapRepoLabelKey: s.chartVersion.owner.Spec.Repository,
+		configMapLabelType:    fileType,
+	}
+}
+
+// listObjects lists configmaps or secrets with files of the given type which are either chunks or not
+func (s storageBase) listObjects(list client.ObjectList, fileType string, chunks bool) error {
+	selector := labels.SelectorFromSet(labels.Set{
+		configMapLabelKey:  s.chart + "-" + s.version,
+		configMapLabelType: fileType,
+	})
+
+	operator := selection.DoesNotExist
+
+	if chunks {
+		operator = selection.Exists
+	}
+
+	requirement, _ := labels.NewRequirement(configMapLabelChunk, operator, nil)
+	selector = selector.Add(*requirement)
+
+	return s.chartVersion.k8sClient.List(context.Background(), list, &client.ListOptions{
+		LabelSelector: selector,
+	})
+}
+
+// configMapStorage stores each template directory in a configmap with a key per file.
+// If a configmap would exceed the size limit, the files are stored like by the compressed storage instead.
+type configMapStorage struct {
+	storageBase
+}
+
+func (s *configMapStorage) save(fileType string, files []*chart.File) error {
+	configmaps := s.getTemplateConfigMaps(fileType, files)
+
+	for _, configmap := range configmaps {
+		size := 0
+
+		for key, data := range configmap.BinaryData {
+			size += len(key) + len(data)
+		}
+
+		// the size of a chunk leaves headroom for the metadata of the configmap
+		if size > chunkSize {
+			s.chartVersion.logger.Info("configmap exceeds size limit, storing files compressed", "configmap", configmap.ObjectMeta.Name, "size", size)
+			return s.compressed().save(fileType, getConfigMapFiles(fileType, configmaps))
+		}
+	}
+
+	for _, configmap := range configmaps {
+		if err := s.chartVersion.deployConfigMap(configmap); err != nil {
+			s.chartVersion.logger.Error(err, "error on creating configmap", "configmap", configmap.ObjectMeta.Name)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *configMapStorage) load(fileType string) ([]*chart.File, error) {
+	configmapList := &v1.ConfigMapList{}
+
+	if err := s.listObjects(configmapList, fileType, false); err != nil {
+		return nil, err
+	}
+
+	if len(configmapList.Items) == 0 {
+		return s.compressed().load(fileType)
+	}
+
+	return getConfigMapFiles(fileType, configmapList.Items), nil
+}
+
+// compressed returns the storage which is used for files exceeding the size limit of a configmap
+func (s *configMapStorage) compressed() *chunkStorage {
+	return &chunkStorage{storageBase: s.storageBase}
+}
+
+// getConfigMapFiles returns the files of template configmaps with their path in the chart
+func getConfigMapFiles(fileType string, configmaps []v1.ConfigMap) []*chart.File {
+	var files []*chart.File
+
+	for _, configmap := range configmaps {
+		baseName := "templates/"
+
+		if fileType == "crds" {
+			baseName = "crds/"
+		}
+
+		if configmap.ObjectMeta.Labels[configMapLabelSubName] != "" {
+			baseName = baseName + configmap.ObjectMeta.Labels[configMapLabelSubName] + "/"
+		}
+
+		for key, data := range configmap.BinaryData {
+			files = append(files, &chart.File{
+				Name: baseName + key,
+				Data: data,
+			})
+		}
+	}
+
+	return files
+}
@@ -27,9 +27,12 @@ type ChartVersion struct {
 	DefaultValues map[string]interface{}
 	k8sClient     client.WithWatch
 	getter        utils.HTTPClientInterface
-	logger        logr.Logger
-	mu            sync.Mutex
-	wg            sync.WaitGroup
+	storage       storage
+	// archive is the downloaded chart archive if the chart was loaded by url
+	archive []byte
+	logger  logr.Logger
+	mu      sync.Mutex
+	wg      sync.WaitGroup
 }
 
 // Auth represents struct with auth data for a repo
@@ -39,4 +42,11 @@ type Auth struct {
 	Cert     string
 	Key      string
 	Ca       string
+	// Token is used as bearer token for http repositories or as https token for git repositories
+	Token string
+	// Headers are added to each http request to the repository
+	Headers map[string]string
+	// SSHKey and KnownHosts are only used for git repositories
+	SSHKey     string
+	KnownHosts string
 }
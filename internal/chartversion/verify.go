@@ -0,0 +1,114 @@
+package chartversion
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
+	"helm.sh/helm/v3/pkg/provenance"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const keyringDataKey = "keyring.gpg"
+
+// ErrVerificationFailed is returned if a chart archive does not match its index digest or provenance file
+var ErrVerificationFailed = errors.New("chart verification failed")
+
+// getVerification returns the verification settings of the chart or its repository
+func (chartVersion *ChartVersion) getVerification() *helmv1alpha1.ChartVerification {
+
+	if chartVersion.owner != nil && chartVersion.owner.Spec.Verify != nil {
+		return chartVersion.owner.Spec.Verify
+	}
+
+	if chartVersion.repo != nil {
+		return chartVersion.repo.Spec.Verify
+	}
+
+	return nil
+}
+
+// verifyArchive checks the archive against the digest of the index and the provenance file if verification is enabled
+func (chartVersion *ChartVersion) verifyArchive(archive, prov []byte) error {
+
+	verification := chartVersion.getVerification()
+
+	if verification == nil {
+		return nil
+	}
+
+	name := path.Base(chartVersion.url)
+
+	if digest := strings.TrimPrefix(chartVersion.Version.Digest, "sha256:"); digest != "" {
+		if sum := fmt.Sprintf("%x", sha256.Sum256(archive)); sum != digest {
+			return fmt.Errorf("%w: digest %v of %v does not match index digest %v", ErrVerificationFailed, sum, name, digest)
+		}
+	}
+
+	if len(prov) == 0 {
+		return fmt.Errorf("%w: no provenance file found for %v", ErrVerificationFailed, name)
+	}
+
+	keyring, err := chartVersion.getKeyring(verification.KeyringSecret)
+
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "yaho-verify-")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.RemoveAll(dir)
+
+	// the provenance file references the archive by its file name
+	files := map[string][]byte{
+		keyringDataKey: keyring,
+		name:           archive,
+		name + ".prov": prov,
+	}
+
+	for file, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, file), data, 0600); err != nil {
+			return err
+		}
+	}
+
+	sig, err := provenance.NewFromKeyring(filepath.Join(dir, keyringDataKey), "")
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := sig.Verify(filepath.Join(dir, name), filepath.Join(dir, name+".prov")); err != nil {
+		return fmt.Errorf("%w: %v", ErrVerificationFailed, err)
+	}
+
+	chartVersion.logger.Info("verified chart archive", "archive", name)
+	return nil
+}
+
+func (chartVersion *ChartVersion) getKeyring(secret string) ([]byte, error) {
+	secretObj := &v1.Secret{}
+
+	if err := chartVersion.k8sClient.Get(context.Background(), types.NamespacedName{Namespace: chartVersion.namespace, Name: secret}, secretObj); err != nil {
+		return nil, err
+	}
+
+	keyring, ok := secretObj.Data[keyringDataKey]
+
+	if !ok {
+		return nil, fmt.Errorf("%w: no %v found in secret %v", ErrVerificationFailed, keyringDataKey, secret)
+	}
+
+	return keyring, nil
+}
@@ -1,17 +1,33 @@
 package cmd
 
 import (
+	"context"
 	"flag"
+	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	helmcontrollers "github.com/soer3n/yaho/controllers/agent"
+	"github.com/soer3n/yaho/internal/release"
 	"github.com/soer3n/yaho/internal/utils"
 	"github.com/spf13/cobra"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/cli"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/yaml"
 )
 
 func NewAgentCmd(scheme *runtime.Scheme) *cobra.Command {
@@ -23,21 +39,253 @@ func NewAgentCmd(scheme *runtime.Scheme) *cobra.Command {
 
 	cmd.AddCommand(newAgentRunCmd(scheme))
 	cmd.AddCommand(newAgentKubeconfigCmd(scheme))
+	cmd.AddCommand(newAgentImportCmd(scheme))
 	return cmd
 }
 
 func newAgentKubeconfigCmd(scheme *runtime.Scheme) *cobra.Command {
 
 	cmd := &cobra.Command{
-		Use:   "kubeconfig",
-		Short: "parse and store agent kubeconfig",
-		Long:  `parse and store agent kubeconfig`,
-		Run: func(cmd *cobra.Command, args []string) {
+		Use:          "kubeconfig",
+		Short:        "parse and store agent kubeconfig",
+		Long:         `parse, validate and store the kubeconfig of a remote cluster in a secret which can be referenced by releases and configs`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, _ := cmd.Flags().GetString("file")
+			name, _ := cmd.Flags().GetString("name")
+			namespace, _ := cmd.Flags().GetString("namespace")
+			kubeContext, _ := cmd.Flags().GetString("context")
+			check, _ := cmd.Flags().GetBool("check")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			return storeKubeconfig(scheme, file, name, namespace, kubeContext, check, dryRun, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().String("file", "", "path of the kubeconfig or - for reading it from stdin")
+	cmd.Flags().String("name", "", "name of the secret")
+	cmd.Flags().String("namespace", "default", "namespace of the secret which has to be the namespace of the releases")
+	cmd.Flags().String("context", "", "context of the kubeconfig which should be stored instead of the current context")
+	cmd.Flags().Bool("check", false, "if true checks whether the api server of the kubeconfig is reachable")
+	cmd.Flags().Bool("dry-run", false, "if true prints the secret instead of storing it")
+	_ = cmd.MarkFlagRequired("file")
+	_ = cmd.MarkFlagRequired("name")
+
+	return cmd
+}
+
+// storeKubeconfig validates a kubeconfig reduced to one context with embedded credentials and creates or updates its secret
+func storeKubeconfig(scheme *runtime.Scheme, file, name, namespace, kubeContext string, check, dryRun bool, out io.Writer) error {
+	var config *clientcmdapi.Config
+	var err error
+
+	if file == "-" {
+		var raw []byte
+
+		if raw, err = io.ReadAll(os.Stdin); err != nil {
+			return err
+		}
+
+		config, err = clientcmd.Load(raw)
+	} else {
+		config, err = clientcmd.LoadFromFile(file)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// relative paths of certificates and keys are resolved by the location of the file
+	if err := clientcmd.ResolveLocalPaths(config); err != nil {
+		return err
+	}
+
+	if err := utils.PrepareKubeconfig(config, kubeContext); err != nil {
+		return err
+	}
+
+	if check {
+		restConfig, err := clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{}).ClientConfig()
+
+		if err != nil {
+			return err
+		}
+
+		discoveryClient, err := discovery.NewDiscoveryClientForConfig(restConfig)
+
+		if err != nil {
+			return err
+		}
+
+		if _, err := discoveryClient.ServerVersion(); err != nil {
+			return fmt.Errorf("api server %v not reachable: %w", utils.GetKubeconfigServer(config), err)
+		}
+	}
+
+	secret, err := utils.NewKubeconfigSecret(name, namespace, config)
+
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		raw, err := yaml.Marshal(secret)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = out.Write(raw)
+		return err
+	}
+
+	c, err := client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+
+	if err != nil {
+		return err
+	}
+
+	current := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+
+	result, err := controllerutil.CreateOrUpdate(context.Background(), c, current, func() error {
+		if current.ObjectMeta.CreationTimestamp.IsZero() {
+			current.Type = secret.Type
+		}
+
+		if current.Labels == nil {
+			current.Labels = map[string]string{}
+		}
+
+		for k, v := range secret.Labels {
+			current.Labels[k] = v
+		}
+
+		current.Data = secret.Data
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "secret %v/%v %v for api server %v\n", namespace, name, result, utils.GetKubeconfigServer(config))
+	return nil
+}
+
+func newAgentImportCmd(scheme *runtime.Scheme) *cobra.Command {
+
+	cmd := &cobra.Command{
+		Use:          "import",
+		Short:        "import helm releases",
+		Long:         `generate repository, chart, values and release resources for deployed helm releases of a namespace so that the agent takes over their upgrades`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, _ := cmd.Flags().GetString("namespace")
+			names, _ := cmd.Flags().GetStringSlice("release")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			opts := release.ImportOptions{}
+			opts.Namespace, _ = cmd.Flags().GetString("target-namespace")
+			opts.Config, _ = cmd.Flags().GetString("config")
+			opts.Repository, _ = cmd.Flags().GetString("repository")
+			opts.URL, _ = cmd.Flags().GetString("url")
+			return importReleases(scheme, namespace, names, opts, dryRun, cmd.OutOrStdout())
 		},
 	}
+
+	cmd.Flags().String("namespace", "default", "namespace of the helm releases")
+	cmd.Flags().StringSlice("release", []string{}, "names of the helm releases which should be imported. Default are all deployed releases of the namespace")
+	cmd.Flags().String("target-namespace", "default", "namespace of the generated release and values resources")
+	cmd.Flags().String("config", "", "name of the config which is referenced by the generated releases")
+	cmd.Flags().String("repository", "", "name of the generated repository")
+	cmd.Flags().String("url", "", "url of the repository which contains the charts of the helm releases")
+	cmd.Flags().Bool("dry-run", false, "if true prints the resources instead of creating them")
+	_ = cmd.MarkFlagRequired("repository")
+	_ = cmd.MarkFlagRequired("url")
+
 	return cmd
 }
 
+// importReleases reads the deployed helm releases of a namespace and creates the resources which take them over.
+// Existing resources are not changed.
+func importReleases(scheme *runtime.Scheme, namespace string, names []string, opts release.ImportOptions, dryRun bool, out io.Writer) error {
+	settings := cli.New()
+	settings.SetNamespace(namespace)
+
+	actionConfig := new(action.Configuration)
+
+	if err := actionConfig.Init(settings.RESTClientGetter(), namespace, "secret", func(format string, v ...interface{}) {}); err != nil {
+		return err
+	}
+
+	list := action.NewList(actionConfig)
+	list.StateMask = action.ListDeployed
+
+	releases, err := list.Run()
+
+	if err != nil {
+		return err
+	}
+
+	selected := []*helmrelease.Release{}
+
+	for _, rel := range releases {
+		if len(names) == 0 || utils.Contains(names, rel.Name) {
+			selected = append(selected, rel)
+		}
+	}
+
+	if len(selected) == 0 {
+		return fmt.Errorf("no deployed helm releases found in namespace %v", namespace)
+	}
+
+	objects, err := release.GetImportedResources(selected, opts)
+
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		for _, obj := range objects {
+			raw, err := yaml.Marshal(obj)
+
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(out, "---\n%s", raw)
+		}
+
+		return nil
+	}
+
+	c, err := client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range objects {
+		kind := obj.GetObjectKind().GroupVersionKind().Kind
+		key := obj.GetName()
+		result := "created"
+
+		if obj.GetNamespace() != "" {
+			key = obj.GetNamespace() + "/" + key
+		}
+
+		if err := c.Create(context.Background(), obj); err != nil {
+			if !errors.IsAlreadyExists(err) {
+				return err
+			}
+
+			result = "unchanged"
+		}
+
+		fmt.Fprintf(out, "%v %v %v\n", strings.ToLower(kind), key, result)
+	}
+
+	return nil
+}
+
 func newAgentRunCmd(scheme *runtime.Scheme) *cobra.Command {
 
 	var metricsAddr string
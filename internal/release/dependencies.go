@@ -0,0 +1,133 @@
+package release
+
+import (
+	"fmt"
+	"sort"
+
+	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
+	meta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ReleaseLookup returns the release resource with the given key or nil if it doesn't exist
+type ReleaseLookup func(key types.NamespacedName) (*helmv1alpha1.Release, error)
+
+// GetDependencyKey returns the key of a dependency which defaults to the namespace of the depending release
+func GetDependencyKey(instance *helmv1alpha1.Release, dependency helmv1alpha1.ReleaseDependency) types.NamespacedName {
+	namespace := dependency.Namespace
+
+	if namespace == "" {
+		namespace = instance.ObjectMeta.Namespace
+	}
+
+	return types.NamespacedName{Namespace: namespace, Name: dependency.Name}
+}
+
+// GetDependencyCycle returns the releases of a cycle which leads back to the release or nil if there is none
+func GetDependencyCycle(instance *helmv1alpha1.Release, lookup ReleaseLookup) ([]string, error) {
+	start := types.NamespacedName{Namespace: instance.ObjectMeta.Namespace, Name: instance.ObjectMeta.Name}
+	visited := map[types.NamespacedName]bool{}
+
+	var visit func(current *helmv1alpha1.Release, path []string) ([]string, error)
+
+	visit = func(current *helmv1alpha1.Release, path []string) ([]string, error) {
+		for _, dependency := range current.Spec.DependsOn {
+			key := GetDependencyKey(current, dependency)
+
+			if key == start {
+				return append(path, key.String()), nil
+			}
+
+			if visited[key] {
+				continue
+			}
+
+			visited[key] = true
+			next, err := lookup(key)
+
+			if err != nil {
+				return nil, err
+			}
+
+			// missing dependencies are reported as not ready
+			if next == nil {
+				continue
+			}
+
+			cycle, err := visit(next, append(path, key.String()))
+
+			if err != nil || cycle != nil {
+				return cycle, err
+			}
+		}
+
+		return nil, nil
+	}
+
+	return visit(instance, []string{start.String()})
+}
+
+// GetUnreadyDependency returns a message about the first dependency which is not ready or an empty string if all are ready
+func GetUnreadyDependency(instance *helmv1alpha1.Release, lookup ReleaseLookup) (string, error) {
+	for _, dependency := range instance.Spec.DependsOn {
+		key := GetDependencyKey(instance, dependency)
+		rel, err := lookup(key)
+
+		if err != nil {
+			return "", err
+		}
+
+		if rel == nil {
+			return fmt.Sprintf("dependency %v not found", key), nil
+		}
+
+		if ready, reason := IsReleaseReady(rel); !ready {
+			return fmt.Sprintf("dependency %v %v", key, reason), nil
+		}
+	}
+
+	return "", nil
+}
+
+// IsReleaseReady returns whether the current generation of the release is synced and healthy or the reason why not
+func IsReleaseReady(rel *helmv1alpha1.Release) (bool, string) {
+
+	if rel.GetDeletionTimestamp() != nil {
+		return false, "is being deleted"
+	}
+
+	synced := meta.FindStatusCondition(rel.Status.Conditions, "synced")
+
+	if synced == nil || synced.Status != metav1.ConditionTrue || synced.ObservedGeneration != rel.Generation {
+		return false, "is not synced"
+	}
+
+	if healthy := meta.FindStatusCondition(rel.Status.Conditions, "healthy"); healthy != nil && healthy.Status == metav1.ConditionFalse {
+		return false, "is not healthy"
+	}
+
+	if tested := meta.FindStatusCondition(rel.Status.Conditions, "tested"); tested != nil && tested.Status == metav1.ConditionFalse {
+		return false, "failed its tests"
+	}
+
+	return true, ""
+}
+
+// GetDependents returns the keys of the releases which depend on the release
+func GetDependents(instance *helmv1alpha1.Release, releases []helmv1alpha1.Release) []string {
+	self := types.NamespacedName{Namespace: instance.ObjectMeta.Namespace, Name: instance.ObjectMeta.Name}
+	dependents := []string{}
+
+	for i := range releases {
+		for _, dependency := range releases[i].Spec.DependsOn {
+			if GetDependencyKey(&releases[i], dependency) == self {
+				dependents = append(dependents, types.NamespacedName{Namespace: releases[i].Namespace, Name: releases[i].Name}.String())
+				break
+			}
+		}
+	}
+
+	sort.Strings(dependents)
+	return dependents
+}
@@ -0,0 +1,273 @@
+package release
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+// DetectDrift returns the resources of the deployed revision which are missing or differ from their manifest
+func (hc *Release) DetectDrift() ([]helmv1alpha1.DriftedResource, error) {
+	drift := []helmv1alpha1.DriftedResource{}
+
+	rel, err := hc.getRelease()
+
+	if err != nil {
+		return drift, err
+	}
+
+	resources, err := hc.Config.KubeClient.Build(bytes.NewBufferString(rel.Manifest), false)
+
+	if err != nil {
+		return drift, err
+	}
+
+	for _, info := range resources {
+		live, err := resource.NewHelper(info.Client, info.Mapping).Get(info.Namespace, info.Name)
+		reason := ""
+
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				return drift, err
+			}
+
+			reason = "missing"
+		} else {
+			if reason, err = GetDriftReason(info.Object, live); err != nil {
+				return drift, err
+			}
+		}
+
+		if reason == "" {
+			continue
+		}
+
+		drift = append(drift, helmv1alpha1.DriftedResource{
+			Kind:      info.Mapping.GroupVersionKind.Kind,
+			Namespace: info.Namespace,
+			Name:      info.Name,
+			Reason:    reason,
+		})
+	}
+
+	hc.logger.Info("drift detected", "resources", len(drift))
+	return drift, nil
+}
+
+// Heal reverts drift by a three-way-merge upgrade with the chart and values of the deployed revision.
+// A newer chart or changed values which are held back e.g. by an upgrade window or a pending plan are not applied.
+func (hc *Release) Heal() error {
+	rel, err := hc.getRelease()
+
+	if err != nil {
+		return err
+	}
+
+	if rel.Chart == nil {
+		return fmt.Errorf("chart of deployed revision %v of release %v not found", rel.Version, hc.Name)
+	}
+
+	hc.logger.Info("revert drift of release", "revision", rel.Version)
+	return hc.upgrade(rel.Chart, rel.Config)
+}
+
+// GetDriftReason returns the path of the first field of the desired object which differs in the live object.
+// Fields which are only set in the live object like defaults or status are ignored.
+// The desired object is normalized like by the api server, i.e. stringData of secrets is encoded into data and quantities are compared by value.
+func GetDriftReason(desired, live runtime.Object) (string, error) {
+
+	desiredMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(desired)
+
+	if err != nil {
+		return "", err
+	}
+
+	desiredMap = normalizeSecret(desiredMap)
+
+	liveMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(live)
+
+	if err != nil {
+		return "", err
+	}
+
+	return diffFields("", desiredMap, liveMap), nil
+}
+
+func diffFields(path string, desired, live interface{}) string {
+
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+
+		if !ok {
+			return pathOrRoot(path)
+		}
+
+		keys := make([]string, 0, len(d))
+
+		for k := range d {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			// empty values of the manifest are omitted by the api server
+			if d[k] == nil || (isEmptyValue(d[k]) && isEmptyValue(l[k])) {
+				continue
+			}
+
+			if reason := diffFields(strings.TrimPrefix(path+"."+k, "."), d[k], l[k]); reason != "" {
+				return reason
+			}
+		}
+
+		return ""
+	case []interface{}:
+		l, ok := live.([]interface{})
+
+		if !ok || len(l) != len(d) {
+			return pathOrRoot(path)
+		}
+
+		for i := range d {
+			if reason := diffFields(fmt.Sprintf("%v[%v]", path, i), d[i], l[i]); reason != "" {
+				return reason
+			}
+		}
+
+		return ""
+	}
+
+	if reflect.DeepEqual(normalizeNumber(desired), normalizeNumber(live)) {
+		return ""
+	}
+
+	if isQuantityPath(path) && isEqualQuantity(desired, live) {
+		return ""
+	}
+
+	return pathOrRoot(path)
+}
+
+// normalizeSecret returns a copy of a secret with stringData encoded into data as it is stored by the api server
+func normalizeSecret(obj map[string]interface{}) map[string]interface{} {
+	stringData, ok := obj["stringData"].(map[string]interface{})
+
+	if obj["kind"] != "Secret" || !ok {
+		return obj
+	}
+
+	normalized := map[string]interface{}{}
+
+	for k, v := range obj {
+		normalized[k] = v
+	}
+
+	data := map[string]interface{}{}
+
+	if current, ok := obj["data"].(map[string]interface{}); ok {
+		for k, v := range current {
+			data[k] = v
+		}
+	}
+
+	for k, v := range stringData {
+		data[k] = base64.StdEncoding.EncodeToString([]byte(fmt.Sprint(v)))
+	}
+
+	normalized["data"] = data
+	delete(normalized, "stringData")
+	return normalized
+}
+
+// isEmptyValue returns whether a value is missing or empty like an empty string, list or map
+func isEmptyValue(v interface{}) bool {
+	switch value := v.(type) {
+	case nil:
+		return true
+	case string:
+		return value == ""
+	case []interface{}:
+		return len(value) == 0
+	case map[string]interface{}:
+		return len(value) == 0
+	}
+
+	return false
+}
+
+// isQuantityPath returns whether a field path points to a resource quantity like resources.limits.cpu or capacity.storage
+func isQuantityPath(path string) bool {
+	segments := strings.Split(path, ".")
+
+	for i, segment := range segments {
+		// list indices are part of the segment like containers[0]
+		segment, _, _ = strings.Cut(segment, "[")
+
+		if (segment == "resources" || segment == "capacity") && i < len(segments)-1 {
+			return true
+		}
+	}
+
+	return segments[len(segments)-1] == "storage"
+}
+
+// isEqualQuantity returns whether both values are quantities with the same value like 1000m and 1 which the api server stores in canonical form
+func isEqualQuantity(desired, live interface{}) bool {
+	desiredQuantity, ok := parseQuantity(desired)
+
+	if !ok {
+		return false
+	}
+
+	liveQuantity, ok := parseQuantity(live)
+
+	if !ok {
+		return false
+	}
+
+	return desiredQuantity.Cmp(liveQuantity) == 0
+}
+
+func parseQuantity(v interface{}) (apiresource.Quantity, bool) {
+	switch v.(type) {
+	case string, int64, int32, int, float64:
+		q, err := apiresource.ParseQuantity(fmt.Sprint(v))
+		return q, err == nil
+	}
+
+	return apiresource.Quantity{}, false
+}
+
+// normalizeNumber converts numbers to float64 because manifests and live objects can be decoded differently
+func normalizeNumber(v interface{}) interface{} {
+	switch n := v.(type) {
+	case int64:
+		return float64(n)
+	case int32:
+		return float64(n)
+	case int:
+		return float64(n)
+	}
+
+	return v
+}
+
+func pathOrRoot(path string) string {
+
+	if path == "" {
+		return "."
+	}
+
+	return path
+}
@@ -1,14 +1,118 @@
 package release
 
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+const (
+	// UninstallDelete uninstalls the helm release and removes its history
+	UninstallDelete = "delete"
+	// UninstallKeepHistory uninstalls the helm release and keeps its history
+	UninstallKeepHistory = "keepHistory"
+	// UninstallOrphan leaves the helm release and its resources untouched
+	UninstallOrphan = "orphan"
+)
+
+const defaultUninstallGiveUpAfter = 600 * time.Second
+
 // RemoveRelease represents func for managing action related to a change of a finalizer related to a release or repo resource
 func (hr *Release) RemoveRelease() error {
-	if _, err := hr.getRelease(); err != nil {
+	policy := hr.getUninstallPolicy()
+
+	if policy.Policy == UninstallOrphan {
+		hr.logger.Info("keep orphaned helm release", "name", hr.Name)
 		return nil
 	}
 
-	if err := hr.Remove(); err != nil {
+	rel, err := hr.getRelease()
+
+	// only a missing release is already gone, other errors are retried until the uninstall is given up
+	if err != nil && !errors.Is(err, driver.ErrReleaseNotFound) {
+		return err
+	}
+
+	// an uninstalled release with kept history has nothing left to remove
+	if err == nil && (rel.Info == nil || rel.Info.Status != release.StatusUninstalled) {
+		if err := hr.uninstall(rel, policy); err != nil {
+			return err
+		}
+	}
+
+	if err := hr.removeNamespace(); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// GetUninstallGiveUpAfter returns the time after the deletion after which a failing uninstall is given up
+func GetUninstallGiveUpAfter(policy *helmv1alpha1.UninstallPolicy) time.Duration {
+
+	if policy != nil && policy.GiveUpAfter > 0 {
+		return time.Duration(policy.GiveUpAfter) * time.Second
+	}
+
+	return defaultUninstallGiveUpAfter
+}
+
+// uninstall removes the helm release as defined by the policy and deletes the CRDs of its chart if requested
+func (hr *Release) uninstall(rel *release.Release, policy *helmv1alpha1.UninstallPolicy) error {
+	client := action.NewUninstall(hr.Config)
+	client.KeepHistory = policy.Policy == UninstallKeepHistory
+	client.Wait = policy.Wait
+	client.DisableHooks = policy.DisableHooks
+	client.Timeout = 300 * time.Second
+
+	if policy.Timeout > 0 {
+		client.Timeout = time.Duration(policy.Timeout) * time.Second
+	}
+
+	if _, err := client.Run(hr.Name); err != nil {
+		return err
+	}
+
+	hr.logger.Info("uninstalled release", "name", hr.Name, "policy", policy.Policy)
+
+	if !policy.DeleteCRDs || client.KeepHistory || rel.Chart == nil {
+		return nil
+	}
+
+	return hr.deleteCRDs(rel)
+}
+
+// deleteCRDs deletes the CRDs which were stored with the chart of the release
+func (hr *Release) deleteCRDs(rel *release.Release) error {
+	for _, crd := range rel.Chart.CRDObjects() {
+		resources, err := hr.Config.KubeClient.Build(bytes.NewBuffer(crd.File.Data), false)
+
+		if err != nil {
+			return fmt.Errorf("failed to build crd %v: %w", crd.Filename, err)
+		}
+
+		if _, errs := hr.Config.KubeClient.Delete(resources); len(errs) > 0 {
+			return fmt.Errorf("failed to delete crd %v: %v", crd.Filename, errs[0])
+		}
+
+		hr.logger.Info("deleted crd of release", "name", hr.Name, "crd", crd.Filename)
+	}
+
+	return nil
+}
+
+// getUninstallPolicy returns the policy of the release or the default one
+func (hr *Release) getUninstallPolicy() *helmv1alpha1.UninstallPolicy {
+
+	if hr.Uninstall == nil {
+		return &helmv1alpha1.UninstallPolicy{Policy: UninstallDelete}
+	}
+
+	return hr.Uninstall
+}
@@ -0,0 +1,261 @@
+package release
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+// AssessHealth returns the health of the resources of the deployed revision which have a builtin check or a matching rule
+func (hc *Release) AssessHealth(rules []helmv1alpha1.HealthRule) ([]helmv1alpha1.ResourceHealth, error) {
+	health := []helmv1alpha1.ResourceHealth{}
+
+	rel, err := hc.getRelease()
+
+	if err != nil {
+		return health, err
+	}
+
+	resources, err := hc.Config.KubeClient.Build(bytes.NewBufferString(rel.Manifest), false)
+
+	if err != nil {
+		return health, err
+	}
+
+	for _, info := range resources {
+		result := helmv1alpha1.ResourceHealth{
+			Kind:      info.Mapping.GroupVersionKind.Kind,
+			Namespace: info.Namespace,
+			Name:      info.Name,
+		}
+
+		live, err := resource.NewHelper(info.Client, info.Mapping).Get(info.Namespace, info.Name)
+
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				return health, err
+			}
+
+			result.Message = "missing"
+			health = append(health, result)
+			continue
+		}
+
+		assessed := false
+
+		if assessed, result.Healthy, result.Message, err = GetResourceHealth(live, rules); err != nil {
+			return health, err
+		}
+
+		if assessed {
+			health = append(health, result)
+		}
+	}
+
+	return health, nil
+}
+
+// GetResourceHealth returns whether the resource is healthy and why not.
+// Resources without a builtin check or a matching rule are not assessed.
+func GetResourceHealth(live runtime.Object, rules []helmv1alpha1.HealthRule) (bool, bool, string, error) {
+	raw, err := runtime.DefaultUnstructuredConverter.ToUnstructured(live)
+
+	if err != nil {
+		return false, false, "", err
+	}
+
+	obj := &unstructured.Unstructured{Object: raw}
+	gvk := obj.GroupVersionKind()
+
+	for _, rule := range rules {
+		if rule.Kind == gvk.Kind && rule.Group == gvk.Group {
+			healthy, message := getRuleHealth(obj, rule)
+			return true, healthy, message, nil
+		}
+	}
+
+	// generations are only tracked by workload controllers
+	if gvk.Group == "apps" || gvk.Group == "batch" {
+		if observed := nestedInt(obj, "status", "observedGeneration"); gvk.Kind != "Job" && observed < obj.GetGeneration() {
+			return true, false, "rollout not observed yet", nil
+		}
+	}
+
+	switch {
+	case gvk.Group == "apps" && gvk.Kind == "Deployment":
+		healthy, message := getDeploymentHealth(obj)
+		return true, healthy, message, nil
+	case gvk.Group == "apps" && gvk.Kind == "StatefulSet":
+		healthy, message := getStatefulSetHealth(obj)
+		return true, healthy, message, nil
+	case gvk.Group == "apps" && gvk.Kind == "DaemonSet":
+		healthy, message := getDaemonSetHealth(obj)
+		return true, healthy, message, nil
+	case gvk.Group == "batch" && gvk.Kind == "Job":
+		healthy, message := getJobHealth(obj)
+		return true, healthy, message, nil
+	case gvk.Group == "" && gvk.Kind == "PersistentVolumeClaim":
+		phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+		return true, phase == "Bound", "phase " + strings.ToLower(orDefault(phase, "pending")), nil
+	case gvk.Group == "" && gvk.Kind == "Service":
+		healthy, message := getServiceHealth(obj)
+		return true, healthy, message, nil
+	}
+
+	return false, false, "", nil
+}
+
+func getDeploymentHealth(obj *unstructured.Unstructured) (bool, string) {
+	replicas := getReplicas(obj)
+	updated := nestedInt(obj, "status", "updatedReplicas")
+	available := nestedInt(obj, "status", "availableReplicas")
+	current := nestedInt(obj, "status", "replicas")
+
+	message := fmt.Sprintf("%v of %v replicas available", available, replicas)
+
+	if updated < replicas || current > updated {
+		return false, fmt.Sprintf("%v of %v replicas updated", updated, replicas)
+	}
+
+	return available >= replicas, message
+}
+
+func getStatefulSetHealth(obj *unstructured.Unstructured) (bool, string) {
+	replicas := getReplicas(obj)
+	ready := nestedInt(obj, "status", "readyReplicas")
+	updated := nestedInt(obj, "status", "updatedReplicas")
+	strategy, _, _ := unstructured.NestedString(obj.Object, "spec", "updateStrategy", "type")
+
+	if strategy != "OnDelete" && updated < replicas {
+		return false, fmt.Sprintf("%v of %v replicas updated", updated, replicas)
+	}
+
+	return ready >= replicas, fmt.Sprintf("%v of %v replicas ready", ready, replicas)
+}
+
+func getDaemonSetHealth(obj *unstructured.Unstructured) (bool, string) {
+	desired := nestedInt(obj, "status", "desiredNumberScheduled")
+	updated := nestedInt(obj, "status", "updatedNumberScheduled")
+	available := nestedInt(obj, "status", "numberAvailable")
+	strategy, _, _ := unstructured.NestedString(obj.Object, "spec", "updateStrategy", "type")
+
+	if strategy != "OnDelete" && updated < desired {
+		return false, fmt.Sprintf("%v of %v pods updated", updated, desired)
+	}
+
+	return available >= desired, fmt.Sprintf("%v of %v pods available", available, desired)
+}
+
+func getJobHealth(obj *unstructured.Unstructured) (bool, string) {
+	if status, message := getCondition(obj, "Failed"); status == "True" {
+		return false, "failed: " + message
+	}
+
+	if status, _ := getCondition(obj, "Complete"); status == "True" {
+		return true, "completed"
+	}
+
+	return false, "running"
+}
+
+func getServiceHealth(obj *unstructured.Unstructured) (bool, string) {
+	serviceType, _, _ := unstructured.NestedString(obj.Object, "spec", "type")
+
+	if serviceType != "LoadBalancer" {
+		return true, ""
+	}
+
+	ingress, _, _ := unstructured.NestedSlice(obj.Object, "status", "loadBalancer", "ingress")
+
+	if len(ingress) == 0 {
+		return false, "load balancer not provisioned"
+	}
+
+	return true, ""
+}
+
+func getRuleHealth(obj *unstructured.Unstructured, rule helmv1alpha1.HealthRule) (bool, string) {
+
+	if rule.Field != "" {
+		value, found, err := unstructured.NestedFieldNoCopy(obj.Object, strings.Split(rule.Field, ".")...)
+
+		if err != nil || !found {
+			return false, rule.Field + " not set"
+		}
+
+		current := fmt.Sprint(value)
+
+		for _, expected := range rule.Values {
+			if current == expected {
+				return true, rule.Field + " is " + current
+			}
+		}
+
+		return false, rule.Field + " is " + current
+	}
+
+	conditionType := orDefault(rule.Condition, "Ready")
+	status, message := getCondition(obj, conditionType)
+
+	if status == "" {
+		return false, "condition " + conditionType + " not set"
+	}
+
+	return status == "True", message
+}
+
+// getCondition returns the status and message of a status condition of the object
+func getCondition(obj *unstructured.Unstructured, conditionType string) (string, string) {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+
+	for _, item := range conditions {
+		condition, ok := item.(map[string]interface{})
+
+		if !ok || condition["type"] != conditionType {
+			continue
+		}
+
+		status, _ := condition["status"].(string)
+		message, _ := condition["message"].(string)
+		return status, message
+	}
+
+	return "", ""
+}
+
+// getReplicas returns the desired replicas which default to one
+func getReplicas(obj *unstructured.Unstructured) int64 {
+	if _, found, _ := unstructured.NestedFieldNoCopy(obj.Object, "spec", "replicas"); !found {
+		return 1
+	}
+
+	return nestedInt(obj, "spec", "replicas")
+}
+
+// nestedInt returns an integer field of objects which were decoded as json or converted from typed objects
+func nestedInt(obj *unstructured.Unstructured, fields ...string) int64 {
+	value, _, _ := unstructured.NestedFieldNoCopy(obj.Object, fields...)
+
+	switch v := normalizeNumber(value).(type) {
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	}
+
+	return 0
+}
+
+func orDefault(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+
+	return value
+}
@@ -0,0 +1,186 @@
+package release
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"github.com/Masterminds/semver/v3"
+	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
+	"github.com/soer3n/yaho/internal/utils"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ImportOptions defines the repository of imported charts and the namespace and config of the generated releases
+type ImportOptions struct {
+	// Namespace of the generated releases and values
+	Namespace  string
+	Config     string
+	Repository string
+	URL        string
+}
+
+// GetImportedResources returns a repository, charts, values and releases which take over the given helm releases.
+// The releases keep their name, namespace, chart version and user supplied values, so that they are upgraded instead of installed again.
+func GetImportedResources(releases []*helmrelease.Release, opts ImportOptions) ([]client.Object, error) {
+
+	if opts.Repository == "" || opts.URL == "" {
+		return nil, errors.New("repository name and url are required")
+	}
+
+	objects := []client.Object{}
+	versions := map[string][]string{}
+	imported := []client.Object{}
+
+	sort.Slice(releases, func(i, j int) bool {
+		return releases[i].Namespace+"/"+releases[i].Name < releases[j].Namespace+"/"+releases[j].Name
+	})
+
+	for _, rel := range releases {
+		if rel.Chart == nil || rel.Chart.Metadata == nil {
+			continue
+		}
+
+		chartName := rel.Chart.Metadata.Name
+
+		if !utils.Contains(versions[chartName], rel.Chart.Metadata.Version) {
+			versions[chartName] = append(versions[chartName], rel.Chart.Metadata.Version)
+		}
+
+		releaseNamespace := rel.Namespace
+		spec := helmv1alpha1.ReleaseSpec{
+			Name:      rel.Name,
+			Namespace: &releaseNamespace,
+			Repo:      opts.Repository,
+			Chart:     chartName,
+			Version:   rel.Chart.Metadata.Version,
+			Values:    []string{},
+		}
+
+		if opts.Config != "" {
+			config := opts.Config
+			spec.Config = &config
+		}
+
+		if len(rel.Config) > 0 {
+			raw, err := json.Marshal(rel.Config)
+
+			if err != nil {
+				return nil, err
+			}
+
+			valuesName := rel.Name + "-values"
+			spec.Values = append(spec.Values, valuesName)
+
+			imported = append(imported, &helmv1alpha1.Values{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: helmv1alpha1.GroupVersion.String(),
+					Kind:       "Values",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      valuesName,
+					Namespace: opts.Namespace,
+				},
+				Spec: helmv1alpha1.ValuesSpec{
+					ValuesMap: &runtime.RawExtension{Raw: raw},
+				},
+			})
+		}
+
+		imported = append(imported, &helmv1alpha1.Release{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: helmv1alpha1.GroupVersion.String(),
+				Kind:       "Release",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      rel.Name,
+				Namespace: opts.Namespace,
+			},
+			Spec: spec,
+		})
+	}
+
+	charts := []string{}
+
+	for chartName := range versions {
+		charts = append(charts, chartName)
+	}
+
+	sort.Strings(charts)
+
+	repository := &helmv1alpha1.Repository{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: helmv1alpha1.GroupVersion.String(),
+			Kind:       "Repository",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: opts.Repository,
+		},
+		Spec: helmv1alpha1.RepositorySpec{
+			Name: opts.Repository,
+			URL:  opts.URL,
+		},
+	}
+
+	objects = append(objects, repository)
+
+	for _, chartName := range charts {
+		sortVersions(versions[chartName])
+		repository.Spec.Charts = append(repository.Spec.Charts, helmv1alpha1.Entry{
+			Name:     chartName,
+			Versions: versions[chartName],
+		})
+
+		// name and labels match the charts which are managed by the repository
+		objects = append(objects, &helmv1alpha1.Chart{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: helmv1alpha1.GroupVersion.String(),
+				Kind:       "Chart",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name: chartName + "-" + opts.Repository,
+				Labels: map[string]string{
+					"yaho.soer3n.dev/repo":  opts.Repository,
+					"yaho.soer3n.dev/chart": chartName,
+				},
+			},
+			Spec: helmv1alpha1.ChartSpec{
+				Name:       chartName,
+				Repository: opts.Repository,
+				Versions:   versions[chartName],
+				CreateDeps: true,
+			},
+		})
+	}
+
+	return append(objects, imported...), nil
+}
+
+// sortVersions sorts chart versions ascending by semver. Invalid versions are sorted lexically after them.
+func sortVersions(versions []string) {
+	parsed := semver.Collection{}
+	invalid := []string{}
+
+	for _, v := range versions {
+		sv, err := semver.NewVersion(v)
+
+		if err != nil {
+			invalid = append(invalid, v)
+			continue
+		}
+
+		parsed = append(parsed, sv)
+	}
+
+	sort.Sort(parsed)
+	sort.Strings(invalid)
+
+	for i, v := range parsed {
+		versions[i] = v.Original()
+	}
+
+	copy(versions[len(parsed):], invalid)
+}
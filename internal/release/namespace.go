@@ -0,0 +1,150 @@
+package release
+
+import (
+	"context"
+
+	"helm.sh/helm/v3/pkg/action"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// namespaceOwnerAnnotation marks namespaces which were created for a release
+const namespaceOwnerAnnotation = "yaho.soer3n.dev/created-by"
+
+// ensureNamespace creates the release namespace if the config allows it
+func (hc *Release) ensureNamespace() error {
+
+	if !hc.Namespace.Install || hc.releaseNamespace == "" {
+		return nil
+	}
+
+	clientset, err := hc.Config.KubernetesClientSet()
+
+	if err != nil {
+		return err
+	}
+
+	owned, err := EnsureNamespace(clientset, hc.releaseNamespace, hc.namespaceOwner(), hc.Namespace.Labels, hc.Namespace.Annotations)
+
+	if err != nil {
+		return err
+	}
+
+	hc.CreatedNamespace = ""
+
+	if owned {
+		hc.CreatedNamespace = hc.releaseNamespace
+	}
+
+	return nil
+}
+
+// removeNamespace deletes the namespace created for the release if no other releases are left in it
+func (hc *Release) removeNamespace() error {
+
+	if hc.CreatedNamespace == "" {
+		return nil
+	}
+
+	client := action.NewList(hc.Config)
+	client.StateMask = action.ListAll
+
+	releases, err := client.Run()
+
+	if err != nil {
+		return err
+	}
+
+	if len(releases) > 0 {
+		hc.logger.Info("keep namespace with remaining releases", "namespace", hc.CreatedNamespace, "releases", len(releases))
+		return nil
+	}
+
+	clientset, err := hc.Config.KubernetesClientSet()
+
+	if err != nil {
+		return err
+	}
+
+	deleted, err := DeleteNamespace(clientset, hc.CreatedNamespace, hc.namespaceOwner())
+
+	if err != nil {
+		return err
+	}
+
+	if deleted {
+		hc.logger.Info("deleted release namespace", "namespace", hc.CreatedNamespace)
+	}
+
+	return nil
+}
+
+func (hc *Release) namespaceOwner() string {
+	return hc.Namespace.Name + "/" + hc.Name
+}
+
+// EnsureNamespace creates the namespace with the given labels and annotations if it is missing.
+// It returns whether the namespace was created by the owner.
+func EnsureNamespace(clientset kubernetes.Interface, name, owner string, labels, annotations map[string]string) (bool, error) {
+	ns, err := clientset.CoreV1().Namespaces().Get(context.Background(), name, metav1.GetOptions{})
+
+	if err == nil {
+		return ns.GetAnnotations()[namespaceOwnerAnnotation] == owner, nil
+	}
+
+	if !errors.IsNotFound(err) {
+		return false, err
+	}
+
+	nsLabels := map[string]string{}
+	nsAnnotations := map[string]string{}
+
+	for k, v := range labels {
+		nsLabels[k] = v
+	}
+
+	for k, v := range annotations {
+		nsAnnotations[k] = v
+	}
+
+	nsAnnotations[namespaceOwnerAnnotation] = owner
+
+	ns = &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Labels:      nsLabels,
+			Annotations: nsAnnotations,
+		},
+	}
+
+	if _, err := clientset.CoreV1().Namespaces().Create(context.Background(), ns, metav1.CreateOptions{}); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// DeleteNamespace deletes the namespace if it was created by the owner and returns whether it was deleted
+func DeleteNamespace(clientset kubernetes.Interface, name, owner string) (bool, error) {
+	ns, err := clientset.CoreV1().Namespaces().Get(context.Background(), name, metav1.GetOptions{})
+
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	if ns.GetAnnotations()[namespaceOwnerAnnotation] != owner {
+		return false, nil
+	}
+
+	if err := clientset.CoreV1().Namespaces().Delete(context.Background(), name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -13,6 +13,22 @@ func (hc *Release) setOptions(instance *helmv1alpha1.Config, namespace *string)
 
 	var rn string
 	hc.Flags = instance.Spec.Flags
+	hc.Namespace.Install = instance.Spec.Namespace.Install
+	hc.Namespace.Labels = instance.Spec.Namespace.Labels
+	hc.Namespace.Annotations = instance.Spec.Namespace.Annotations
+
+	// the policy of the release takes precedence over the one of the config
+	if hc.Remediation == nil && hc.Flags != nil {
+		hc.Remediation = hc.Flags.Remediation
+	}
+
+	if hc.PostRenderer == nil {
+		hc.PostRenderer = instance.Spec.PostRenderer
+	}
+
+	if hc.Uninstall == nil {
+		hc.Uninstall = instance.Spec.Uninstall
+	}
 
 	if namespace == nil {
 		rn = instance.ObjectMeta.Namespace
@@ -0,0 +1,331 @@
+package release
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// maxDiffSize keeps the diff below the size limit of the configmap
+const maxDiffSize = v1.MaxSecretSize - 1024
+
+const (
+	// redactedValue replaces the values of secrets in the diff
+	redactedValue = "<redacted>"
+	// redactedChangedValue replaces the values of secrets which differ from the deployed ones
+	redactedChangedValue = "<redacted:changed>"
+)
+
+// Plan represents the changes which an install or upgrade of the release would apply
+type Plan struct {
+	helmv1alpha1.ReleasePlan
+	Diff string
+}
+
+type manifestHead struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Metadata   struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+}
+
+// Plan renders the manifest with the loaded chart and values and compares it with the deployed revision.
+// Nothing is planned if an installed release has no pending upgrade.
+func (hc *Release) Plan() (*Plan, error) {
+	current, _ := hc.getRelease()
+
+	inputs, err := hc.getPlanInputs(current)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if current != nil {
+		pending, err := hc.needsUpgrade(current)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !pending {
+			return &Plan{ReleasePlan: helmv1alpha1.ReleasePlan{Inputs: inputs}}, nil
+		}
+	}
+
+	manifest, err := hc.renderManifest(current)
+
+	if err != nil {
+		return nil, err
+	}
+
+	deployed := ""
+
+	if current != nil {
+		deployed = current.Manifest
+	}
+
+	plan, err := GetManifestDiff(deployed, manifest)
+
+	if err != nil {
+		return nil, err
+	}
+
+	plan.Inputs = inputs
+	hc.logger.Info("planned release changes", "digest", plan.Digest, "added", len(plan.Added), "changed", len(plan.Changed), "removed", len(plan.Removed))
+	return plan, nil
+}
+
+// PlanInputs returns the fingerprint of the deployed revision, chart, values and post renderer a plan is rendered for.
+// A plan is kept until it is approved or its inputs change, because charts with random or looked up values render differently each time.
+func (hc *Release) PlanInputs() (string, error) {
+	current, _ := hc.getRelease()
+	return hc.getPlanInputs(current)
+}
+
+func (hc *Release) getPlanInputs(current *release.Release) (string, error) {
+	inputs := struct {
+		Revision     int                        `json:"revision"`
+		Chart        string                     `json:"chart"`
+		Version      string                     `json:"version"`
+		Values       map[string]interface{}     `json:"values"`
+		PostRenderer *helmv1alpha1.PostRenderer `json:"postRenderer,omitempty"`
+	}{
+		PostRenderer: hc.PostRenderer,
+	}
+
+	if current != nil {
+		inputs.Revision = current.Version
+	}
+
+	if hc.Chart != nil && hc.Chart.Metadata != nil {
+		inputs.Chart = hc.Chart.Metadata.Name
+		inputs.Version = hc.Chart.Metadata.Version
+	}
+
+	if hc.ValuesTemplate != nil {
+		inputs.Values = hc.ValuesTemplate.Values
+	}
+
+	raw, err := json.Marshal(inputs)
+
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(raw))[:16], nil
+}
+
+// renderManifest returns the manifest of a dry run install or upgrade
+func (hc *Release) renderManifest(current *release.Release) (string, error) {
+	var rel *release.Release
+	var err error
+
+	if current == nil {
+		client := action.NewInstall(hc.Config)
+		client.ReleaseName = hc.Name
+		client.Namespace = hc.releaseNamespace
+		client.PostRenderer = hc.getPostRenderer()
+		hc.setInstallFlags(client)
+		client.DryRun = true
+
+		rel, err = client.Run(hc.Chart, hc.ValuesTemplate.Values)
+	} else {
+		client := action.NewUpgrade(hc.Config)
+		client.Namespace = hc.releaseNamespace
+		client.PostRenderer = hc.getPostRenderer()
+		hc.setUpgradeFlags(client)
+		client.DryRun = true
+
+		rel, err = client.Run(hc.Name, hc.Chart, hc.ValuesTemplate.Values)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return rel.Manifest, nil
+}
+
+// GetManifestDiff returns the added, changed and removed objects and a unified diff between two manifests.
+// The values of secrets are redacted in the diff and in the digest. The digest is empty if nothing changed.
+func GetManifestDiff(deployed, desired string) (*Plan, error) {
+	plan := &Plan{}
+
+	current, err := splitManifest(deployed)
+
+	if err != nil {
+		return plan, err
+	}
+
+	target, err := splitManifest(desired)
+
+	if err != nil {
+		return plan, err
+	}
+
+	keys := []string{}
+
+	for key := range current {
+		keys = append(keys, key)
+	}
+
+	for key := range target {
+		if _, ok := current[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+	diffs := []string{}
+
+	for _, key := range keys {
+		deployedDoc, inCurrent := current[key]
+		desiredDoc, inTarget := target[key]
+
+		if strings.HasPrefix(key, "Secret/") {
+			if deployedDoc, desiredDoc, err = redactSecret(deployedDoc, desiredDoc); err != nil {
+				return plan, err
+			}
+		}
+
+		switch {
+		case !inCurrent:
+			plan.Added = append(plan.Added, key)
+		case !inTarget:
+			plan.Removed = append(plan.Removed, key)
+		case deployedDoc != desiredDoc:
+			plan.Changed = append(plan.Changed, key)
+		default:
+			continue
+		}
+
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(deployedDoc),
+			B:        difflib.SplitLines(desiredDoc),
+			FromFile: "deployed/" + key,
+			ToFile:   "planned/" + key,
+			Context:  3,
+		})
+
+		if err != nil {
+			return plan, err
+		}
+
+		diffs = append(diffs, diff)
+	}
+
+	if len(diffs) == 0 {
+		return plan, nil
+	}
+
+	plan.Diff = strings.Join(diffs, "")
+	plan.Digest = fmt.Sprintf("%x", sha256.Sum256([]byte(plan.Diff)))[:16]
+
+	if len(plan.Diff) > maxDiffSize {
+		plan.Diff = plan.Diff[:maxDiffSize] + "\n# diff truncated\n"
+	}
+
+	return plan, nil
+}
+
+// splitManifest returns the documents of a manifest by kind, namespace and name
+func splitManifest(manifest string) (map[string]string, error) {
+	objects := map[string]string{}
+
+	for _, doc := range releaseutil.SplitManifests(manifest) {
+		head := manifestHead{}
+
+		if err := yaml.Unmarshal([]byte(doc), &head); err != nil {
+			return objects, err
+		}
+
+		if head.Kind == "" || head.Metadata.Name == "" {
+			continue
+		}
+
+		key := strings.Join([]string{head.Kind, head.Metadata.Namespace, head.Metadata.Name}, "/")
+
+		if head.Metadata.Namespace == "" {
+			key = head.Kind + "/" + head.Metadata.Name
+		}
+
+		objects[key] = strings.TrimSpace(doc) + "\n"
+	}
+
+	return objects, nil
+}
+
+// redactSecret replaces the values of data and stringData of the deployed and desired version of a secret.
+// Desired values which differ from the deployed ones are marked as changed.
+func redactSecret(deployed, desired string) (string, string, error) {
+	deployedObj := map[string]interface{}{}
+	desiredObj := map[string]interface{}{}
+
+	if err := yaml.Unmarshal([]byte(deployed), &deployedObj); err != nil {
+		return "", "", err
+	}
+
+	if err := yaml.Unmarshal([]byte(desired), &desiredObj); err != nil {
+		return "", "", err
+	}
+
+	for _, field := range []string{"data", "stringData"} {
+		deployedValues, _ := deployedObj[field].(map[string]interface{})
+		desiredValues, _ := desiredObj[field].(map[string]interface{})
+
+		for key, value := range desiredValues {
+			desiredValues[key] = redactedChangedValue
+
+			if current, ok := deployedValues[key]; ok && reflect.DeepEqual(current, value) {
+				desiredValues[key] = redactedValue
+			}
+		}
+
+		for key := range deployedValues {
+			deployedValues[key] = redactedValue
+		}
+	}
+
+	deployedDoc, err := marshalRedacted(deployed, deployedObj)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	desiredDoc, err := marshalRedacted(desired, desiredObj)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	return deployedDoc, desiredDoc, nil
+}
+
+// marshalRedacted returns the redacted object of a document or nothing for a missing document
+func marshalRedacted(doc string, obj map[string]interface{}) (string, error) {
+
+	if doc == "" {
+		return "", nil
+	}
+
+	raw, err := yaml.Marshal(obj)
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(raw), nil
+}
@@ -0,0 +1,172 @@
+package release
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
+	"helm.sh/helm/v3/pkg/postrender"
+	"helm.sh/helm/v3/pkg/release"
+	"sigs.k8s.io/kustomize/api/konfig"
+	"sigs.k8s.io/kustomize/api/krusty"
+	kustypes "sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/kustomize/kyaml/resid"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	postRenderDir       = "/postrender"
+	postRenderResources = "resources.yaml"
+	// postRendererLabel stores a hash of the applied post renderer in the helm release, so that changes trigger an upgrade
+	postRendererLabel = "yaho.soer3n.dev/post-renderer"
+)
+
+// PostRenderer applies patches and common metadata to rendered manifests with an in-memory kustomization
+type PostRenderer struct {
+	spec *helmv1alpha1.PostRenderer
+}
+
+// NewPostRenderer returns a helm post renderer for the spec or nil if nothing has to be modified
+func NewPostRenderer(spec *helmv1alpha1.PostRenderer) postrender.PostRenderer {
+	if spec == nil || (len(spec.Patches) == 0 && len(spec.CommonLabels) == 0 && len(spec.CommonAnnotations) == 0) {
+		return nil
+	}
+
+	return &PostRenderer{spec: spec}
+}
+
+// Run builds the kustomization of the rendered manifests and returns the modified manifests
+func (p *PostRenderer) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+
+	// charts which only contain hooks render no objects
+	if len(bytes.TrimSpace(renderedManifests.Bytes())) == 0 {
+		return renderedManifests, nil
+	}
+
+	fs := filesys.MakeFsInMemory()
+
+	if err := fs.MkdirAll(postRenderDir); err != nil {
+		return nil, err
+	}
+
+	if err := fs.WriteFile(postRenderDir+"/"+postRenderResources, renderedManifests.Bytes()); err != nil {
+		return nil, err
+	}
+
+	kustomization, err := yaml.Marshal(p.kustomization())
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := fs.WriteFile(postRenderDir+"/"+konfig.DefaultKustomizationFileName(), kustomization); err != nil {
+		return nil, err
+	}
+
+	resMap, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(fs, postRenderDir)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to post render manifests: %w", err)
+	}
+
+	manifests, err := resMap.AsYaml()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewBuffer(manifests), nil
+}
+
+func (p *PostRenderer) kustomization() *kustypes.Kustomization {
+	kustomization := &kustypes.Kustomization{
+		TypeMeta: kustypes.TypeMeta{
+			APIVersion: kustypes.KustomizationVersion,
+			Kind:       kustypes.KustomizationKind,
+		},
+		Resources:         []string{postRenderResources},
+		CommonLabels:      p.spec.CommonLabels,
+		CommonAnnotations: p.spec.CommonAnnotations,
+	}
+
+	for _, patch := range p.spec.Patches {
+		kustomizePatch := kustypes.Patch{
+			Patch: patch.Patch,
+		}
+
+		if patch.Target != nil {
+			kustomizePatch.Target = &kustypes.Selector{
+				ResId: resid.ResId{
+					Gvk: resid.Gvk{
+						Group:   patch.Target.Group,
+						Version: patch.Target.Version,
+						Kind:    patch.Target.Kind,
+					},
+					Name:      patch.Target.Name,
+					Namespace: patch.Target.Namespace,
+				},
+				LabelSelector:      patch.Target.LabelSelector,
+				AnnotationSelector: patch.Target.AnnotationSelector,
+			}
+		}
+
+		kustomization.Patches = append(kustomization.Patches, kustomizePatch)
+	}
+
+	return kustomization
+}
+
+// getPostRenderer returns the post renderer of the release or nil if none is configured
+func (hc *Release) getPostRenderer() postrender.PostRenderer {
+	return NewPostRenderer(hc.PostRenderer)
+}
+
+// getPostRendererHash returns a hash of the post renderer of the release or nothing if none is configured
+func (hc *Release) getPostRendererHash() (string, error) {
+
+	if NewPostRenderer(hc.PostRenderer) == nil {
+		return "", nil
+	}
+
+	raw, err := json.Marshal(hc.PostRenderer)
+
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(raw))[:16], nil
+}
+
+// getPostRendererLabels returns the release labels for an install or upgrade.
+// The label of a removed post renderer is set to null, which deletes it on upgrade.
+func (hc *Release) getPostRendererLabels(upgrade bool) (map[string]string, error) {
+	hash, err := hc.getPostRendererHash()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if hash != "" {
+		return map[string]string{postRendererLabel: hash}, nil
+	}
+
+	if upgrade {
+		return map[string]string{postRendererLabel: "null"}, nil
+	}
+
+	return nil, nil
+}
+
+// postRendererChanged returns whether the post renderer differs from the one applied to the deployed release
+func (hc *Release) postRendererChanged(rel *release.Release) (bool, error) {
+	hash, err := hc.getPostRendererHash()
+
+	if err != nil {
+		return false, err
+	}
+
+	return rel.Labels[postRendererLabel] != hash, nil
+}
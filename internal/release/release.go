@@ -3,6 +3,7 @@ package release
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
 	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
@@ -35,14 +36,21 @@ func New(instance *helmv1alpha1.Release, watchNamespace string, scheme *runtime.
 		Namespace: Namespace{
 			Name: instance.ObjectMeta.Namespace,
 		},
-		Version:   instance.Spec.Version,
-		Repo:      instance.Spec.Repo,
-		K8sClient: k8sclient,
-		scheme:    scheme,
-		getter:    g,
-		logger:    reqLogger.WithValues("release", instance.Spec.Name),
-		wg:        &sync.WaitGroup{},
-		mu:        sync.Mutex{},
+		Version:          instance.Spec.Version,
+		Repo:             instance.Spec.Repo,
+		RollbackTo:       instance.Spec.RollbackTo,
+		Rollback:         instance.Status.Rollback,
+		Generation:       instance.Generation,
+		Remediation:      instance.Spec.Remediation,
+		PostRenderer:     instance.Spec.PostRenderer,
+		Uninstall:        instance.Spec.Uninstall,
+		CreatedNamespace: instance.Status.CreatedNamespace,
+		K8sClient:        k8sclient,
+		scheme:           scheme,
+		getter:           g,
+		logger:           reqLogger.WithValues("release", instance.Spec.Name),
+		wg:               &sync.WaitGroup{},
+		mu:               sync.Mutex{},
 	}
 
 	helmRelease.releaseNamespace = instance.ObjectMeta.Namespace
@@ -95,8 +103,12 @@ func New(instance *helmv1alpha1.Release, watchNamespace string, scheme *runtime.
 		return helmRelease, err
 	}
 
+	if err := helmRelease.setTargetVersion(instance.Spec.Version, index, instance.Spec.Upgrade, time.Now()); err != nil {
+		return helmRelease, err
+	}
+
 	options := &action.ChartPathOptions{
-		Version:               instance.Spec.Version,
+		Version:               helmRelease.Version,
 		InsecureSkipTLSverify: false,
 		Verify:                false,
 	}
@@ -133,34 +145,51 @@ func (hc *Release) Update() error {
 
 	hc.logger.Info("configupdate: "+fmt.Sprint(hc.Config), "name", hc.Name, "repo", hc.Repo)
 
+	if err := hc.ensureNamespace(); err != nil {
+		return err
+	}
+
 	release, _ = hc.getRelease()
 
 	// Check if something changed regarding the existing release
 	if release != nil {
-		if ok, err = hc.valuesChanged(); err != nil {
+		if hc.RollbackTo != nil {
+			if err := hc.rollbackToSpec(release); err != nil {
+				return err
+			}
+
+			return hc.setHistory()
+		}
+
+		if ok, err = hc.needsUpgrade(release); err != nil {
 			return err
 		}
 
 		hc.Revision = release.Version
 
 		if ok {
-			if err := hc.upgrade(hc.Chart); err != nil {
+			if err := hc.upgrade(hc.Chart, hc.ValuesTemplate.Values); err != nil {
 				return err
 			}
 			hc.logger.Info("release updated.", "name", release.Name, "namespace", release.Namespace, "chart", hc.Chart.Name(), "repo", hc.Repo)
-			return nil
+			return hc.setHistory()
 		}
 
 		hc.logger.Info("nothing changed for release.", "name", release.Name, "namespace", release.Namespace, "chart", hc.Chart.Name(), "repo", hc.Repo)
-		return nil
+		return hc.setHistory()
 	}
 
 	client := action.NewInstall(installConfig)
 	client.ReleaseName = hc.Name
 	client.Namespace = hc.releaseNamespace
 	client.CreateNamespace = false
+	client.PostRenderer = hc.getPostRenderer()
 	hc.setInstallFlags(client)
 
+	if client.Labels, err = hc.getPostRendererLabels(false); err != nil {
+		return err
+	}
+
 	if release, err = client.Run(hc.Chart, hc.ValuesTemplate.Values); err != nil {
 		hc.logger.Error(err, "error on installing release", "release", hc.Name, "chart", hc.Chart.Name(), "repo", hc.Repo)
 		return err
@@ -169,7 +198,7 @@ func (hc *Release) Update() error {
 	hc.Revision = release.Version
 
 	hc.logger.Info("release successfully installed.", "name", release.Name, "namespace", release.Namespace, "chart", hc.Chart.Name(), "repo", hc.Repo)
-	return nil
+	return hc.setHistory()
 }
 
 // Remove represents removing release related resource
@@ -185,16 +214,46 @@ func (hc *Release) getRelease() (*release.Release, error) {
 	return client.Run(hc.Name)
 }
 
-func (hc *Release) upgrade(helmChart *helmchart.Chart) error {
+// needsUpgrade returns whether the loaded values or chart version differ from the deployed release
+func (hc *Release) needsUpgrade(rel *release.Release) (bool, error) {
+	ok, err := hc.valuesChanged()
+
+	if err != nil {
+		return false, err
+	}
+
+	if rel.Chart != nil && rel.Chart.Metadata != nil && rel.Chart.Metadata.Version != hc.Chart.Metadata.Version {
+		hc.logger.Info("chart version changed.", "name", rel.Name, "deployed", rel.Chart.Metadata.Version, "version", hc.Chart.Metadata.Version)
+		ok = true
+	}
+
+	changed, err := hc.postRendererChanged(rel)
+
+	if err != nil {
+		return false, err
+	}
+
+	if changed {
+		hc.logger.Info("post renderer changed.", "name", rel.Name)
+		ok = true
+	}
+
+	return ok, nil
+}
+
+func (hc *Release) upgrade(helmChart *helmchart.Chart, vals map[string]interface{}) error {
 	var rel *release.Release
 	var err error
 
-	vals := hc.ValuesTemplate.Values
-
 	client := action.NewUpgrade(hc.Config)
 	client.Namespace = hc.releaseNamespace
+	client.PostRenderer = hc.getPostRenderer()
 	hc.setUpgradeFlags(client)
 
+	if client.Labels, err = hc.getPostRendererLabels(true); err != nil {
+		return err
+	}
+
 	if rel, err = client.Run(hc.Name, helmChart, vals); err != nil {
 		hc.logger.Info(err.Error())
 		return err
@@ -202,6 +261,6 @@ func (hc *Release) upgrade(helmChart *helmchart.Chart) error {
 
 	hc.Revision = rel.Version
 
-	hc.logger.Info("successfully upgraded.", "name", rel.Name, "chart", hc.Chart.Name(), "repo", hc.Repo)
+	hc.logger.Info("successfully upgraded.", "name", rel.Name, "chart", helmChart.Name(), "repo", hc.Repo)
 	return nil
 }
@@ -0,0 +1,142 @@
+package release
+
+import (
+	"time"
+
+	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+const defaultRemediationBackoff = 10
+
+// maxRemediationDelay limits the exponential backoff between retries
+const maxRemediationDelay = 10 * time.Minute
+
+// GetRemediationDelay returns the delay before the retry after the given number of failures
+func GetRemediationDelay(policy *helmv1alpha1.RemediationPolicy, failures int) time.Duration {
+	backoff := int64(defaultRemediationBackoff)
+
+	if policy != nil && policy.Backoff > 0 {
+		backoff = policy.Backoff
+	}
+
+	delay := time.Duration(backoff) * time.Second
+
+	for i := 1; i < failures; i++ {
+		delay *= 2
+
+		if delay >= maxRemediationDelay {
+			return maxRemediationDelay
+		}
+	}
+
+	if delay > maxRemediationDelay {
+		return maxRemediationDelay
+	}
+
+	return delay
+}
+
+// GetRetryWait returns the time left until a failed install or upgrade of the current generation can be retried
+func GetRetryWait(instance *helmv1alpha1.Release, now time.Time) time.Duration {
+	next := instance.Status.NextRetryAt
+
+	if next == nil || instance.Status.FailedGeneration != instance.Generation || !now.Before(next.Time) {
+		return 0
+	}
+
+	return next.Sub(now)
+}
+
+// Remediate uninstalls a failed install or rolls a failed upgrade back to the last successful revision
+// if the policy allows it. It returns the applied action or an empty string if nothing was done.
+func (hc *Release) Remediate() (string, error) {
+
+	if hc.Remediation == nil {
+		return "", nil
+	}
+
+	current, err := hc.getRelease()
+
+	if err != nil {
+		// the failure happened before a release was stored
+		hc.logger.Info("no release to remediate", "name", hc.Name)
+		return "", nil
+	}
+
+	if current.Info == nil || current.Info.Status == release.StatusDeployed {
+		return "", nil
+	}
+
+	if current.Version == 1 {
+		if !hc.Remediation.UninstallOnFailure {
+			return "", nil
+		}
+
+		hc.logger.Info("uninstall failed release", "name", hc.Name)
+
+		if err := hc.Remove(); err != nil {
+			return "", err
+		}
+
+		hc.Revision = 0
+		return "uninstalled", nil
+	}
+
+	if !hc.Remediation.RollbackOnFailure {
+		return "", nil
+	}
+
+	rolledBack, err := hc.rollbackToLastSuccessful(current)
+
+	if err != nil || !rolledBack {
+		return "", err
+	}
+
+	return "rolledBack", nil
+}
+
+// rollbackToLastSuccessful rolls the release back to the newest successful revision before the current one.
+// It returns false if there is no such revision.
+func (hc *Release) rollbackToLastSuccessful(current *release.Release) (bool, error) {
+	revision, err := hc.getLastSuccessfulRevision(current.Version)
+
+	if err != nil {
+		return false, err
+	}
+
+	if revision == 0 {
+		hc.logger.Info("no successful revision to roll back to", "name", hc.Name)
+		return false, nil
+	}
+
+	if err := hc.rollback(revision); err != nil {
+		return false, err
+	}
+
+	return true, hc.setHistory()
+}
+
+// getLastSuccessfulRevision returns the newest revision before the current one which was deployed successfully
+func (hc *Release) getLastSuccessfulRevision(current int) (int, error) {
+	releases, err := action.NewHistory(hc.Config).Run(hc.Name)
+
+	if err != nil {
+		return 0, err
+	}
+
+	revision := 0
+
+	for _, rel := range releases {
+		if rel.Version >= current || rel.Version <= revision || rel.Info == nil {
+			continue
+		}
+
+		if rel.Info.Status == release.StatusDeployed || rel.Info.Status == release.StatusSuperseded {
+			revision = rel.Version
+		}
+	}
+
+	return revision, nil
+}
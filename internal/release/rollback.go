@@ -0,0 +1,113 @@
+package release
+
+import (
+	"sort"
+	"time"
+
+	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// maxHistory is the number of revisions shown in the status
+const maxHistory = 10
+
+// rollbackToSpec rolls the release back to the revision of the spec unless the recorded rollback of the current generation is still deployed
+func (hc *Release) rollbackToSpec(current *release.Release) error {
+	revision := *hc.RollbackTo
+
+	if applied := hc.Rollback; applied != nil && applied.Revision == revision && applied.Generation == hc.Generation && applied.DeployedRevision == current.Version {
+		hc.logger.Info("release already rolled back.", "name", hc.Name, "revision", revision)
+		hc.Revision = current.Version
+		return nil
+	}
+
+	if err := hc.rollback(revision); err != nil {
+		return err
+	}
+
+	hc.Rollback = &helmv1alpha1.ReleaseRollback{
+		Revision:         revision,
+		Generation:       hc.Generation,
+		DeployedRevision: hc.Revision,
+	}
+
+	return nil
+}
+
+// rollback rolls the release back to the revision
+func (hc *Release) rollback(revision int) error {
+	client := action.NewRollback(hc.Config)
+	client.Version = revision
+
+	if hc.Flags != nil {
+		client.DisableHooks = hc.Flags.DisableHooks
+		client.Timeout = hc.Flags.Timeout
+		client.Wait = hc.Flags.Wait
+		client.Force = hc.Flags.Force
+		client.Recreate = hc.Flags.Recreate
+		client.CleanupOnFail = hc.Flags.CleanupOnFail
+	}
+
+	if err := client.Run(hc.Name); err != nil {
+		hc.logger.Info(err.Error())
+		return err
+	}
+
+	rel, err := hc.getRelease()
+
+	if err != nil {
+		return err
+	}
+
+	hc.Revision = rel.Version
+
+	hc.logger.Info("successfully rolled back.", "name", hc.Name, "revision", revision)
+	return nil
+}
+
+// setHistory sets the latest revisions of the release starting with the newest
+func (hc *Release) setHistory() error {
+	client := action.NewHistory(hc.Config)
+	client.Max = maxHistory
+
+	releases, err := client.Run(hc.Name)
+
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(releases, func(i, j int) bool {
+		return releases[i].Version > releases[j].Version
+	})
+
+	if len(releases) > maxHistory {
+		releases = releases[:maxHistory]
+	}
+
+	history := []helmv1alpha1.ReleaseRevision{}
+
+	for _, rel := range releases {
+		revision := helmv1alpha1.ReleaseRevision{
+			Revision: rel.Version,
+		}
+
+		if rel.Chart != nil && rel.Chart.Metadata != nil {
+			revision.Chart = rel.Chart.Metadata.Name
+			revision.Version = rel.Chart.Metadata.Version
+			revision.AppVersion = rel.Chart.Metadata.AppVersion
+		}
+
+		if rel.Info != nil {
+			revision.Status = rel.Info.Status.String()
+			revision.Updated = metav1.NewTime(rel.Info.LastDeployed.Time.Truncate(time.Second))
+			revision.Description = rel.Info.Description
+		}
+
+		history = append(history, revision)
+	}
+
+	hc.History = history
+	return nil
+}
@@ -0,0 +1,110 @@
+package release
+
+import (
+	"sort"
+	"time"
+
+	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// maxSummaryNotesSize and maxSummaryResources limit the size of the summary in the status
+	maxSummaryNotesSize = 4 * 1024
+	maxSummaryResources = 100
+)
+
+// Summary represents the deployed revision. Data contains the notes and resources for a configmap
+// if they exceed the limits of the status and is nil otherwise.
+type Summary struct {
+	helmv1alpha1.ReleaseSummary
+	Data map[string]string
+}
+
+// GetSummary returns the chart, notes and resources of the deployed revision
+func (hc *Release) GetSummary() (*Summary, error) {
+	rel, err := hc.getRelease()
+
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{
+		ReleaseSummary: helmv1alpha1.ReleaseSummary{
+			Revision: rel.Version,
+		},
+	}
+
+	if rel.Chart != nil && rel.Chart.Metadata != nil {
+		summary.Chart = rel.Chart.Metadata.Name
+		summary.Version = rel.Chart.Metadata.Version
+		summary.AppVersion = rel.Chart.Metadata.AppVersion
+	}
+
+	if rel.Info != nil {
+		summary.Deployed = metav1.NewTime(rel.Info.LastDeployed.Time.Truncate(time.Second))
+		summary.Notes = rel.Info.Notes
+	}
+
+	if summary.Resources, err = GetManifestResources(rel.Manifest); err != nil {
+		return nil, err
+	}
+
+	summary.ResourceCount = len(summary.Resources)
+
+	if len(summary.Notes) <= maxSummaryNotesSize && len(summary.Resources) <= maxSummaryResources {
+		return summary, nil
+	}
+
+	resources, err := yaml.Marshal(summary.Resources)
+
+	if err != nil {
+		return nil, err
+	}
+
+	summary.Data = map[string]string{
+		"NOTES.txt":      summary.Notes,
+		"resources.yaml": string(resources),
+	}
+
+	summary.Notes = ""
+	summary.Resources = nil
+
+	return summary, nil
+}
+
+// GetManifestResources returns the resources of a manifest in the order of the manifest
+func GetManifestResources(manifest string) ([]helmv1alpha1.DeployedResource, error) {
+	docs := releaseutil.SplitManifests(manifest)
+	resources := []helmv1alpha1.DeployedResource{}
+	keys := []string{}
+
+	for key := range docs {
+		keys = append(keys, key)
+	}
+
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+
+	for _, key := range keys {
+		head := manifestHead{}
+
+		if err := yaml.Unmarshal([]byte(docs[key]), &head); err != nil {
+			return resources, err
+		}
+
+		if head.Kind == "" || head.Metadata.Name == "" {
+			continue
+		}
+
+		resources = append(resources, helmv1alpha1.DeployedResource{
+			APIVersion: head.APIVersion,
+			Kind:       head.Kind,
+			Namespace:  head.Metadata.Namespace,
+			Name:       head.Metadata.Name,
+		})
+	}
+
+	return resources, nil
+}
@@ -0,0 +1,236 @@
+package release
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	testPhaseSucceeded = "succeeded"
+	testPhaseFailed    = "failed"
+)
+
+const (
+	// maxTestLogSize limits the logs of a test pod
+	maxTestLogSize = 64 * 1024
+	// maxTestLogsSize limits the logs of all test pods so that they fit into a configmap
+	maxTestLogsSize = 512 * 1024
+)
+
+// TestRuns executes tests of releases in the background, so that reconciles are not blocked while tests are running.
+// The zero value is ready to use.
+type TestRuns struct {
+	mu   sync.Mutex
+	runs map[string]*testRun
+}
+
+type testRun struct {
+	done   bool
+	result *TestResult
+	err    error
+}
+
+// TestResult represents the results of the test hooks of the deployed revision and the logs of their pods
+type TestResult struct {
+	helmv1alpha1.ReleaseTestStatus
+	Logs map[string]string
+}
+
+// Run returns the result of the finished test run of the key and removes it.
+// Without a run of the key the tests of the release are started in the background. Nil is returned while the tests are running.
+func (t *TestRuns) Run(key string, hc *Release, timeout time.Duration, trigger string) (*TestResult, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.runs == nil {
+		t.runs = map[string]*testRun{}
+	}
+
+	if run, ok := t.runs[key]; ok {
+		if !run.done {
+			return nil, nil
+		}
+
+		delete(t.runs, key)
+		return run.result, run.err
+	}
+
+	run := &testRun{}
+	t.runs[key] = run
+
+	go func() {
+		result, err := hc.RunTests(timeout)
+
+		if result != nil {
+			result.Trigger = trigger
+		}
+
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		run.result = result
+		run.err = err
+		run.done = true
+	}()
+
+	return nil, nil
+}
+
+// Forget drops the run of the key, e.g. after the release was deleted. A running test is not canceled.
+func (t *TestRuns) Forget(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.runs, key)
+}
+
+// RunTests executes the test hooks of the deployed revision. Failed tests are part of the result and not returned as error.
+func (hc *Release) RunTests(timeout time.Duration) (*TestResult, error) {
+	client := action.NewReleaseTesting(hc.Config)
+	client.Namespace = hc.releaseNamespace
+	client.Timeout = timeout
+
+	rel, runErr := client.Run(hc.Name)
+
+	if rel == nil {
+		return nil, runErr
+	}
+
+	result := &TestResult{
+		ReleaseTestStatus: helmv1alpha1.ReleaseTestStatus{
+			Revision: rel.Version,
+			Phase:    testPhaseSucceeded,
+		},
+		Logs: map[string]string{},
+	}
+
+	if runErr != nil {
+		hc.logger.Info("tests of release failed", "name", hc.Name, "error", runErr.Error())
+		result.Phase = testPhaseFailed
+	}
+
+	for _, hook := range rel.Hooks {
+		if !isTestHook(hook) {
+			continue
+		}
+
+		testResult := helmv1alpha1.ReleaseTestResult{
+			Name:      hook.Name,
+			Phase:     strings.ToLower(string(hook.LastRun.Phase)),
+			Started:   metav1.NewTime(hook.LastRun.StartedAt.Time.Truncate(time.Second)),
+			Completed: metav1.NewTime(hook.LastRun.CompletedAt.Time.Truncate(time.Second)),
+		}
+
+		if hook.LastRun.Phase == release.HookPhaseFailed {
+			result.Phase = testPhaseFailed
+		}
+
+		result.Results = append(result.Results, testResult)
+	}
+
+	hc.setTestLogs(rel, result)
+
+	hc.logger.Info("tests of release finished", "name", hc.Name, "revision", rel.Version, "phase", result.Phase)
+	return result, nil
+}
+
+// RollbackFailedTests rolls the release back to the last successful revision before the tested one
+func (hc *Release) RollbackFailedTests() (bool, error) {
+	current, err := hc.getRelease()
+
+	if err != nil {
+		return false, err
+	}
+
+	return hc.rollbackToLastSuccessful(current)
+}
+
+// setTestLogs adds the logs of the test pods which are still present
+func (hc *Release) setTestLogs(rel *release.Release, result *TestResult) {
+
+	if hc.Config.RESTClientGetter == nil {
+		return
+	}
+
+	clientset, err := hc.Config.KubernetesClientSet()
+
+	if err != nil {
+		hc.logger.Info("no logs of test pods", "error", err.Error())
+		return
+	}
+
+	logs := map[string][]byte{}
+
+	for _, hook := range rel.Hooks {
+		if !isTestHook(hook) || hook.Kind != "Pod" {
+			continue
+		}
+
+		raw, err := clientset.CoreV1().Pods(hc.releaseNamespace).GetLogs(hook.Name, &v1.PodLogOptions{}).DoRaw(context.Background())
+
+		if err != nil {
+			raw = []byte(err.Error())
+		}
+
+		logs[hook.Name] = raw
+	}
+
+	result.Logs = GetLimitedTestLogs(logs)
+}
+
+// GetLimitedTestLogs limits the logs of each test pod and of all test pods.
+// The end of the logs is kept as it is more likely to contain the failure.
+// Invalid UTF-8 is replaced and logs are cut on a rune boundary as configmap data has to be valid UTF-8.
+func GetLimitedTestLogs(logs map[string][]byte) map[string]string {
+	limited := map[string]string{}
+	names := []string{}
+
+	for name := range logs {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	remaining := maxTestLogsSize
+
+	for _, name := range names {
+		raw := strings.ToValidUTF8(string(logs[name]), string(utf8.RuneError))
+		limit := maxTestLogSize
+
+		if remaining < limit {
+			limit = remaining
+		}
+
+		if len(raw) > limit {
+			raw = raw[len(raw)-limit:]
+
+			for len(raw) > 0 && !utf8.RuneStart(raw[0]) {
+				raw = raw[1:]
+			}
+		}
+
+		remaining -= len(raw)
+		limited[name] = raw
+	}
+
+	return limited
+}
+
+func isTestHook(hook *release.Hook) bool {
+	for _, event := range hook.Events {
+		if event == release.HookTest {
+			return true
+		}
+	}
+
+	return false
+}
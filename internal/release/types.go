@@ -2,6 +2,7 @@ package release
 
 import (
 	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
 	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
@@ -15,11 +16,29 @@ import (
 
 // Release represents data needed for installing and updating a helm release
 type Release struct {
-	Name             string
-	Repo             string
-	Chart            *helmchart.Chart
-	Version          string
-	Revision         int
+	Name     string
+	Repo     string
+	Chart    *helmchart.Chart
+	Version  string
+	Revision int
+	// UpgradeDelay is the duration until a pending upgrade can be applied
+	UpgradeDelay time.Duration
+	// RollbackTo is the revision the release should be rolled back to
+	RollbackTo *int
+	// Rollback is the last rollback applied for RollbackTo
+	Rollback *helmv1alpha1.ReleaseRollback
+	// Generation is the generation of the release resource
+	Generation int64
+	// Remediation defines retries and remediation of failed installs and upgrades
+	Remediation *helmv1alpha1.RemediationPolicy
+	// PostRenderer defines patches and common metadata applied to the rendered manifests
+	PostRenderer *helmv1alpha1.PostRenderer
+	// Uninstall defines how the helm release is removed on deletion
+	Uninstall *helmv1alpha1.UninstallPolicy
+	// CreatedNamespace is the release namespace if it was created for the release
+	CreatedNamespace string
+	// History lists the latest revisions starting with the newest
+	History          []helmv1alpha1.ReleaseRevision
 	ValuesTemplate   *values.ValueTemplate
 	Namespace        Namespace
 	releaseNamespace string
@@ -56,6 +75,8 @@ type kubernetes struct {
 
 // Namespace represents struct with release namespace name and if it should be installed
 type Namespace struct {
-	Name    string
-	Install bool
+	Name        string
+	Install     bool
+	Labels      map[string]string
+	Annotations map[string]string
 }
@@ -0,0 +1,228 @@
+package release
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// setTargetVersion sets the chart version which should be deployed regarding the version constraint, the deployed version and the upgrade policy
+func (hc *Release) setTargetVersion(constraint string, index repo.ChartVersions, policy *helmv1alpha1.UpgradePolicy, now time.Time) error {
+	var deployed string
+
+	if rel, _ := hc.getRelease(); rel != nil && rel.Chart != nil && rel.Chart.Metadata != nil {
+		deployed = rel.Chart.Metadata.Version
+	}
+
+	version, delay, err := GetTargetVersion(constraint, deployed, index, policy, now)
+
+	if err != nil {
+		return err
+	}
+
+	if delay > 0 {
+		hc.logger.Info("upgrade postponed until maintenance window", "deployed", deployed, "delay", delay)
+	}
+
+	if version != "" {
+		hc.Version = version
+	}
+
+	hc.UpgradeDelay = delay
+	return nil
+}
+
+// GetTargetVersion returns the version matching the constraint which should be deployed and the delay of a pending upgrade.
+// A deployed version which still matches is only upgraded as far as the policy allows it.
+func GetTargetVersion(constraint, deployed string, index repo.ChartVersions, policy *helmv1alpha1.UpgradePolicy, now time.Time) (string, time.Duration, error) {
+
+	if constraint == "" {
+		return "", 0, nil
+	}
+
+	// an invalid maintenance window must not allow upgrades at any time
+	if policy != nil && policy.MaintenanceWindow != nil {
+		if _, _, err := parseWindow(policy.MaintenanceWindow); err != nil {
+			return "", 0, err
+		}
+	}
+
+	c, err := semver.NewConstraint(constraint)
+
+	if err != nil {
+		return "", 0, err
+	}
+
+	latest := getLatestVersion(c, index, nil)
+
+	if latest == nil {
+		return "", 0, nil
+	}
+
+	current, err := semver.NewVersion(deployed)
+
+	// an explicit change of the constraint is always applied
+	if err != nil || !c.Check(current) {
+		return latest.Original(), 0, nil
+	}
+
+	next := getLatestVersion(c, index, func(v *semver.Version) bool {
+		return v.GreaterThan(current) && isUpgradeAllowed(policy, current, v)
+	})
+
+	if next == nil {
+		return current.Original(), 0, nil
+	}
+
+	delay, err := getWindowDelay(policy.MaintenanceWindow, now)
+
+	if err != nil {
+		return "", 0, err
+	}
+
+	if delay > 0 {
+		return current.Original(), delay, nil
+	}
+
+	return next.Original(), 0, nil
+}
+
+func getLatestVersion(c *semver.Constraints, index repo.ChartVersions, filter func(v *semver.Version) bool) *semver.Version {
+	var latest *semver.Version
+
+	for _, e := range index {
+		v, err := semver.NewVersion(e.Version)
+
+		if err != nil || !c.Check(v) {
+			continue
+		}
+
+		if filter != nil && !filter(v) {
+			continue
+		}
+
+		if latest == nil || v.GreaterThan(latest) {
+			latest = v
+		}
+	}
+
+	return latest
+}
+
+func isUpgradeAllowed(policy *helmv1alpha1.UpgradePolicy, current, v *semver.Version) bool {
+
+	if policy == nil {
+		return false
+	}
+
+	switch policy.Type {
+	case "patch":
+		return v.Major() == current.Major() && v.Minor() == current.Minor()
+	case "minor":
+		return v.Major() == current.Major()
+	case "any":
+		return true
+	}
+
+	return false
+}
+
+// weekdays are the abbreviations of the days of a maintenance window
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// parseWindow returns the start and the duration of a maintenance window
+func parseWindow(window *helmv1alpha1.MaintenanceWindow) (time.Duration, time.Duration, error) {
+	start, err := time.Parse("15:04", window.Start)
+
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid start %q of maintenance window, expected HH:MM", window.Start)
+	}
+
+	end, err := time.Parse("15:04", window.End)
+
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid end %q of maintenance window, expected HH:MM", window.End)
+	}
+
+	for _, d := range window.Days {
+		if _, ok := weekdays[strings.ToLower(string(d))]; !ok {
+			return 0, 0, fmt.Errorf("invalid day %q of maintenance window, expected one of Mon, Tue, Wed, Thu, Fri, Sat, Sun", d)
+		}
+	}
+
+	startOffset := time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute
+	duration := time.Duration(end.Hour())*time.Hour + time.Duration(end.Minute())*time.Minute - startOffset
+
+	if duration == 0 {
+		return 0, 0, fmt.Errorf("maintenance window from %v to %v is empty", window.Start, window.End)
+	}
+
+	// window spans midnight
+	if duration < 0 {
+		duration += 24 * time.Hour
+	}
+
+	return startOffset, duration, nil
+}
+
+// getWindowDelay returns the duration until the maintenance window opens or zero if it is open
+func getWindowDelay(window *helmv1alpha1.MaintenanceWindow, now time.Time) (time.Duration, error) {
+
+	if window == nil {
+		return 0, nil
+	}
+
+	startOffset, duration, err := parseWindow(window)
+
+	if err != nil {
+		return 0, err
+	}
+
+	now = now.UTC().Truncate(time.Minute)
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	// a window belongs to the day it starts on, so the window of the previous day could still be open
+	for day := -1; day <= 7; day++ {
+		open := midnight.AddDate(0, 0, day).Add(startOffset)
+
+		if !isWindowDay(window, open.Weekday()) || !now.Before(open.Add(duration)) {
+			continue
+		}
+
+		if now.Before(open) {
+			return open.Sub(now), nil
+		}
+
+		return 0, nil
+	}
+
+	// not reached as a valid window opens at least once a week
+	return 24 * time.Hour, nil
+}
+
+func isWindowDay(window *helmv1alpha1.MaintenanceWindow, weekday time.Weekday) bool {
+
+	if len(window.Days) == 0 {
+		return true
+	}
+
+	for _, d := range window.Days {
+		if weekdays[strings.ToLower(string(d))] == weekday {
+			return true
+		}
+	}
+
+	return false
+}
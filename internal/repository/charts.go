@@ -57,13 +57,12 @@ func (hr *Repo) deployChart(instance *helmv1alpha1.Repository, chart helmv1alpha
 		return nil
 	}
 
+	// only the fields owned by the repository are set, so that e.g. suspend and verify of the chart are kept
 	c = &charts.Items[0]
-	c.Spec = helmv1alpha1.ChartSpec{
-		Name:       chart.Name,
-		Versions:   chart.Versions,
-		Repository: instance.ObjectMeta.Name,
-		CreateDeps: true,
-	}
+	c.Spec.Name = chart.Name
+	c.Spec.Versions = chart.Versions
+	c.Spec.Repository = instance.ObjectMeta.Name
+	c.Spec.CreateDeps = true
 
 	if err := hr.K8sClient.Update(context.Background(), c); err != nil {
 		hr.logger.Info("could not update chart resource", "chart", chart.Name, "error", err.Error())
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// getSkipReason returns why a chart of the index is not synced or an empty string if it should be synced
+func getSkipReason(instance *helmv1alpha1.Repository, chart string) (string, error) {
+
+	if len(instance.Spec.Charts) > 0 {
+		listed := false
+
+		for _, sc := range instance.Spec.Charts {
+			if sc.Name == chart {
+				listed = true
+				break
+			}
+		}
+
+		if !listed {
+			return "not listed in repository charts", nil
+		}
+	}
+
+	filter := instance.Spec.Filter
+
+	if filter == nil {
+		return "", nil
+	}
+
+	for _, pattern := range filter.Exclude {
+		ok, err := matchChart(pattern, chart)
+
+		if err != nil {
+			return "", err
+		}
+
+		if ok {
+			return "excluded by filter " + pattern, nil
+		}
+	}
+
+	if len(filter.Include) == 0 {
+		return "", nil
+	}
+
+	for _, pattern := range filter.Include {
+		ok, err := matchChart(pattern, chart)
+
+		if err != nil {
+			return "", err
+		}
+
+		if ok {
+			return "", nil
+		}
+	}
+
+	return "not included by filter", nil
+}
+
+// filterVersions returns the chart versions matching the constraint of the filter for the chart
+func filterVersions(filter *helmv1alpha1.ChartFilter, chart string, versions repo.ChartVersions) (repo.ChartVersions, string, error) {
+
+	if filter == nil {
+		return versions, "", nil
+	}
+
+	constraint, ok := filter.Versions[chart]
+
+	if !ok {
+		return versions, "", nil
+	}
+
+	c, err := semver.NewConstraint(constraint)
+
+	if err != nil {
+		return versions, "", errors.Wrapf(err, "invalid version constraint for chart %v", chart)
+	}
+
+	filtered := repo.ChartVersions{}
+
+	for _, v := range versions {
+		parsed, err := semver.NewVersion(v.Version)
+
+		if err != nil || !c.Check(parsed) {
+			continue
+		}
+
+		filtered = append(filtered, v)
+	}
+
+	if len(filtered) == 0 {
+		return filtered, "no version matches constraint " + constraint, nil
+	}
+
+	return filtered, "", nil
+}
+
+// matchChart matches the chart name against a glob pattern or a regular expression enclosed in slashes
+func matchChart(pattern, chart string) (bool, error) {
+
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+
+		if err != nil {
+			return false, errors.Wrapf(err, "invalid chart filter %v", pattern)
+		}
+
+		return re.MatchString(chart), nil
+	}
+
+	ok, err := path.Match(pattern, chart)
+
+	if err != nil {
+		return false, errors.Wrapf(err, "invalid chart filter %v", pattern)
+	}
+
+	return ok, nil
+}
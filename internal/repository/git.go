@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
+	"github.com/soer3n/yaho/internal/utils"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+func (hr *Repo) getIndexByGit(instance *helmv1alpha1.Repository) (*repo.IndexFile, error) {
+	var auth *utils.GitAuth
+
+	obj := repo.NewIndexFile()
+
+	if hr.Auth != nil {
+		auth = &utils.GitAuth{
+			User:       hr.Auth.User,
+			Token:      hr.Auth.Token,
+			SSHKey:     hr.Auth.SSHKey,
+			KnownHosts: hr.Auth.KnownHosts,
+		}
+	}
+
+	hr.logger.Info("checkout git repository", "url", hr.URL, "ref", utils.GetGitRef(instance.Spec.Git))
+
+	err := utils.WithGitCheckout(hr.Name, hr.URL, instance.Spec.Git, auth, func(dir, revision string) error {
+		charts := map[string]string{}
+		root, err := utils.JoinGitPath(dir, instance.Spec.Git.Path)
+
+		if err != nil {
+			return err
+		}
+
+		found, err := utils.FindGitCharts(root)
+
+		if err != nil {
+			return err
+		}
+
+		for _, path := range found {
+			c, err := loader.LoadDir(path)
+
+			if err != nil {
+				hr.logger.Error(err, "error on loading chart", "path", path)
+				continue
+			}
+
+			charts[c.Name()] = path
+		}
+
+		// explicit chart paths overwrite the charts found by searching the repository
+		for _, entry := range instance.Spec.Charts {
+			if entry.Path == "" {
+				continue
+			}
+
+			path, err := utils.JoinGitPath(dir, entry.Path)
+
+			if err != nil || path == dir {
+				hr.logger.Info("chart path is outside of git repository", "chart", entry.Name, "path", entry.Path)
+				continue
+			}
+
+			charts[entry.Name] = path
+		}
+
+		for name, path := range charts {
+			c, err := loader.LoadDir(path)
+
+			if err != nil {
+				hr.logger.Error(err, "error on loading chart", "chart", name, "path", path)
+				continue
+			}
+
+			rel, err := filepath.Rel(dir, path)
+
+			if err != nil {
+				return err
+			}
+
+			// the digest is left empty as the commit is no digest of a chart archive
+			metadata := *c.Metadata
+			metadata.Annotations = map[string]string{}
+
+			for k, v := range c.Metadata.Annotations {
+				metadata.Annotations[k] = v
+			}
+
+			metadata.Annotations[utils.GitRevisionAnnotation] = revision
+
+			// the chart path within the repository is used as url for loading the chart later
+			obj.Entries[name] = append(obj.Entries[name], &repo.ChartVersion{
+				Metadata: &metadata,
+				URLs:     []string{filepath.ToSlash(rel)},
+			})
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return obj, errors.Wrapf(err, "error on checkout of git repository %v with url %v", hr.Name, hr.URL)
+	}
+
+	obj.SortEntries()
+
+	return obj, nil
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"github.com/pkg/errors"
+	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
+	"github.com/soer3n/yaho/internal/utils"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+func (hr *Repo) getIndexByRegistry(instance *helmv1alpha1.Repository) (*repo.IndexFile, error) {
+	var user, password string
+
+	obj := repo.NewIndexFile()
+
+	if hr.Auth != nil {
+		user = hr.Auth.User
+		password = hr.Auth.Password
+	}
+
+	c, err := utils.NewRegistryClient(hr.URL, user, password, instance.Spec.PlainHTTP, hr.tlsConfig)
+
+	if err != nil {
+		return obj, errors.Wrapf(err, "error on initializing registry client for %v with url %v", hr.Name, hr.URL)
+	}
+
+	// oci registries have no index file and cannot be listed, so charts have to be specified
+	if len(instance.Spec.Charts) == 0 {
+		hr.logger.Info("no charts specified for oci repository", "url", hr.URL)
+	}
+
+	for _, entry := range instance.Spec.Charts {
+		ref := utils.GetRegistryRef(hr.URL, entry.Name)
+
+		hr.logger.Info("list tags", "ref", ref)
+
+		tags, err := c.Tags(ref)
+
+		if err != nil {
+			return obj, errors.Wrapf(err, "error on listing tags for %v", ref)
+		}
+
+		for _, tag := range tags {
+
+			// only the config layer with the chart metadata is needed here
+			res, err := c.Pull(ref+":"+tag,
+				registry.PullOptWithChart(false),
+				registry.PullOptWithProv(true),
+				registry.PullOptIgnoreMissingProv(true),
+			)
+
+			if err != nil {
+				hr.logger.Error(err, "error on pulling chart metadata", "ref", ref, "tag", tag)
+				continue
+			}
+
+			obj.Entries[entry.Name] = append(obj.Entries[entry.Name], &repo.ChartVersion{
+				Metadata: res.Chart.Meta,
+				URLs:     []string{registry.OCIScheme + "://" + ref + ":" + tag},
+			})
+		}
+	}
+
+	obj.SortEntries()
+
+	return obj, nil
+}
@@ -1,9 +1,12 @@
 package repository
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
 	b64 "encoding/base64"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/url"
@@ -20,6 +23,7 @@ import (
 	"github.com/soer3n/yaho/internal/utils"
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/registry"
 	"helm.sh/helm/v3/pkg/repo"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -49,14 +53,15 @@ func New(instance *helmv1alpha1.Repository, namespace string, ctx context.Contex
 			Name:    namespace,
 			Install: false,
 		},
-		Settings:   settings,
-		K8sClient:  k8sclient,
-		getter:     g,
-		helmClient: c,
-		logger:     reqLogger.WithValues("repo", instance.Spec.Name),
-		wg:         &sync.WaitGroup{},
-		mu:         sync.Mutex{},
-		ctx:        ctx,
+		Settings:           settings,
+		K8sClient:          k8sclient,
+		getter:             g,
+		helmClient:         c,
+		logger:             reqLogger.WithValues("repo", instance.Spec.Name),
+		wg:                 &sync.WaitGroup{},
+		mu:                 sync.Mutex{},
+		ctx:                ctx,
+		passCredentialsAll: instance.Spec.PassCredentialsAll,
 	}
 
 	if instance.Spec.AuthSecret != "" {
@@ -80,26 +85,86 @@ func New(instance *helmv1alpha1.Repository, namespace string, ctx context.Contex
 		creds.User = string(username)
 		creds.Password = string(pw)
 
+		token, _ := b64.StdEncoding.DecodeString(string(secretObj.Data["token"]))
+		sshKey, _ := b64.StdEncoding.DecodeString(string(secretObj.Data["sshKey"]))
+		knownHosts, _ := b64.StdEncoding.DecodeString(string(secretObj.Data["knownHosts"]))
+		headers, _ := b64.StdEncoding.DecodeString(string(secretObj.Data["headers"]))
+
 		helmRepo.Auth = &Auth{
-			User:     strings.TrimSuffix(string(username), "\n"),
-			Password: strings.TrimSuffix(string(pw), "\n"),
+			User:       strings.TrimSuffix(string(username), "\n"),
+			Password:   strings.TrimSuffix(string(pw), "\n"),
+			Token:      strings.TrimSuffix(string(token), "\n"),
+			Headers:    utils.ParseAuthHeaders(string(headers)),
+			SSHKey:     string(sshKey),
+			KnownHosts: string(knownHosts),
+		}
+	}
+
+	if instance.Spec.TLSSecret != "" {
+		ca, cert, key, err := utils.GetTLSSecretData(k8sclient, instance.Spec.TLSSecret, namespace)
+
+		if err != nil {
+			reqLogger.Info("could not load tls secret", "secret", instance.Spec.TLSSecret, "error", err.Error())
+			helmRepo.indexErr = err
+			return helmRepo
+		}
+
+		if helmRepo.Auth == nil {
+			helmRepo.Auth = &Auth{}
 		}
+
+		helmRepo.Auth.Ca = ca
+		helmRepo.Auth.Cert = cert
+		helmRepo.Auth.Key = key
+	}
+
+	if err := helmRepo.setTLSConfig(instance.Spec.InsecureSkipTLSVerify); err != nil {
+		reqLogger.Info("could not parse tls config", "error", err.Error())
+		helmRepo.indexErr = err
+		return helmRepo
 	}
 
-	indexFile, err := helmRepo.getIndexByURL()
+	// conditional requests are only valid as long as the spec has not changed since the last sync
+	// and the index configmaps of the last sync still exist
+	if instance.Status.ObservedGeneration == instance.ObjectMeta.Generation && helmRepo.hasIndexConfigmaps(instance) {
+		helmRepo.ETag = instance.Status.ETag
+		helmRepo.LastModified = instance.Status.LastModified
+	}
+
+	var indexFile *repo.IndexFile
+	var err error
+
+	if instance.Spec.Git != nil {
+		indexFile, err = helmRepo.getIndexByGit(instance)
+	} else if registry.IsOCI(helmRepo.URL) {
+		indexFile, err = helmRepo.getIndexByRegistry(instance)
+	} else {
+		indexFile, err = helmRepo.getIndexByURL()
+	}
 
 	if err != nil {
 		helmRepo.logger.Error(err, "error on getting repo index file")
 	}
 
+	if err == nil && !helmRepo.NotModified && helmRepo.Digest == "" {
+		helmRepo.Digest, err = getIndexDigest(indexFile)
+	}
+
 	helmRepo.index = indexFile
+	helmRepo.indexErr = err
 
 	return helmRepo
 }
 
 func (hr *Repo) Update(instance *helmv1alpha1.Repository, scheme *runtime.Scheme) error {
 
-	if err := hr.createIndexConfigmaps(instance, scheme); err != nil {
+	if hr.indexErr != nil {
+		return hr.indexErr
+	}
+
+	if hr.NotModified {
+		hr.logger.Info("index not modified since last sync", "repo", hr.Name)
+	} else if err := hr.createIndexConfigmaps(instance, scheme); err != nil {
 		return err
 	}
 
@@ -139,6 +204,11 @@ func (hr *Repo) Update(instance *helmv1alpha1.Repository, scheme *runtime.Scheme
 			}
 		}
 
+		// charts excluded by filter are removed as well
+		if reason, _ := getSkipReason(instance, item.Spec.Name); reason != "" {
+			contains = false
+		}
+
 		if !contains {
 			hr.logger.Info("deleting chart", "repo", hr.Name, "chart", item.ObjectMeta.Name)
 
@@ -151,6 +221,11 @@ func (hr *Repo) Update(instance *helmv1alpha1.Repository, scheme *runtime.Scheme
 	}
 
 	for _, chart := range instance.Spec.Charts {
+		if reason, err := getSkipReason(instance, chart.Name); err != nil || reason != "" {
+			hr.logger.Info("skip chart", "chart", chart.Name, "reason", reason)
+			continue
+		}
+
 		if err := hr.deployChart(repo, chart, scheme); err != nil {
 			return err
 		}
@@ -163,22 +238,29 @@ func (hr *Repo) Update(instance *helmv1alpha1.Repository, scheme *runtime.Scheme
 
 func (hr *Repo) createIndexConfigmaps(instance *helmv1alpha1.Repository, scheme *runtime.Scheme) error {
 
+	synced := map[string]bool{}
+	hr.SkippedCharts = map[string]string{}
+
 	for chart, versions := range hr.index.Entries {
 
-		if len(instance.Spec.Charts) > 0 {
-			ok := false
-			for _, sc := range instance.Spec.Charts {
-				if sc.Name == chart {
-					ok = true
-					break
-				}
-			}
+		reason, err := getSkipReason(instance, chart)
 
-			if !ok {
-				continue
+		if err != nil {
+			return err
+		}
+
+		if reason == "" {
+			if versions, reason, err = filterVersions(instance.Spec.Filter, chart, versions); err != nil {
+				return err
 			}
 		}
 
+		if reason != "" {
+			hr.SkippedCharts[chart] = reason
+			continue
+		}
+
+		synced[chart] = true
 		list, err := json.Marshal(versions)
 
 		if err != nil {
@@ -209,16 +291,99 @@ func (hr *Repo) createIndexConfigmaps(instance *helmv1alpha1.Repository, scheme
 		}
 
 		if err := hr.K8sClient.Create(hr.ctx, cm); err != nil {
-			if k8serrors.IsAlreadyExists(err) {
-				hr.logger.Info("chart configmap already exists", "chart", chart, "name", cm.ObjectMeta.Name)
-				if err := hr.K8sClient.Update(hr.ctx, cm); err != nil {
-					hr.logger.Info("could not update repository index chart configmap", "chart", chart, "name", cm.ObjectMeta.Name)
-					return err
-				}
-				hr.logger.Info("chart configmap of repository index configmap updated", "chart", chart, "name", cm.ObjectMeta.Name)
-				return nil
+			if !k8serrors.IsAlreadyExists(err) {
+				hr.logger.Info("could not create repository index chart configmap", "chart", chart, "name", cm.ObjectMeta.Name)
+				return err
+			}
+
+			hr.logger.Info("chart configmap already exists", "chart", chart, "name", cm.ObjectMeta.Name)
+			current := &v1.ConfigMap{}
+
+			if err := hr.K8sClient.Get(hr.ctx, types.NamespacedName{Name: cm.ObjectMeta.Name, Namespace: cm.ObjectMeta.Namespace}, current); err != nil {
+				return err
+			}
+
+			// skip update if version list has not changed
+			if bytes.Equal(current.BinaryData["versions"], list) {
+				hr.logger.Info("chart versions of repository index configmap not changed", "chart", chart, "name", cm.ObjectMeta.Name)
+				continue
 			}
-			hr.logger.Info("chart configmap of repository index configmap created", "chart", chart, "name", cm.ObjectMeta.Name)
+
+			current.BinaryData = cm.BinaryData
+
+			if err := hr.K8sClient.Update(hr.ctx, current); err != nil {
+				hr.logger.Info("could not update repository index chart configmap", "chart", chart, "name", cm.ObjectMeta.Name)
+				return err
+			}
+
+			hr.logger.Info("chart configmap of repository index configmap updated", "chart", chart, "name", cm.ObjectMeta.Name)
+			continue
+		}
+
+		hr.logger.Info("chart configmap of repository index configmap created", "chart", chart, "name", cm.ObjectMeta.Name)
+	}
+
+	return hr.cleanupIndexConfigmaps(synced)
+}
+
+// hasIndexConfigmaps returns whether index configmaps exist for the repository and all charts of the spec which are not skipped
+func (hr *Repo) hasIndexConfigmaps(instance *helmv1alpha1.Repository) bool {
+
+	configmaps := &v1.ConfigMapList{}
+	opts := &client.ListOptions{
+		Namespace: hr.Namespace.Name,
+		LabelSelector: labels.SelectorFromSet(map[string]string{
+			configMapRepoLabelKey: hr.Name,
+			configMapLabelType:    "index",
+		}),
+	}
+
+	if err := hr.K8sClient.List(hr.ctx, configmaps, opts); err != nil || len(configmaps.Items) == 0 {
+		return false
+	}
+
+	present := map[string]bool{}
+
+	for _, item := range configmaps.Items {
+		present[item.ObjectMeta.Labels[configMapLabelKey]] = true
+	}
+
+	for _, chart := range instance.Spec.Charts {
+		if _, skipped := instance.Status.SkippedCharts[chart.Name]; !skipped && !present[chart.Name] {
+			hr.logger.Info("index configmap is missing", "chart", chart.Name)
+			return false
+		}
+	}
+
+	return true
+}
+
+// cleanupIndexConfigmaps removes index configmaps of charts which are not synced anymore
+func (hr *Repo) cleanupIndexConfigmaps(synced map[string]bool) error {
+
+	configmaps := &v1.ConfigMapList{}
+	opts := &client.ListOptions{
+		Namespace: hr.Namespace.Name,
+		LabelSelector: labels.SelectorFromSet(map[string]string{
+			configMapRepoLabelKey: hr.Name,
+			configMapLabelType:    "index",
+		}),
+	}
+
+	if err := hr.K8sClient.List(hr.ctx, configmaps, opts); err != nil {
+		return err
+	}
+
+	for _, item := range configmaps.Items {
+		if synced[item.ObjectMeta.Labels[configMapLabelKey]] {
+			continue
+		}
+
+		hr.logger.Info("deleting repository index chart configmap", "chart", item.ObjectMeta.Labels[configMapLabelKey], "name", item.ObjectMeta.Name)
+
+		obj := item.DeepCopy()
+		if err := hr.K8sClient.Delete(hr.ctx, obj); err != nil && !k8serrors.IsNotFound(err) {
+			return err
 		}
 	}
 
@@ -258,19 +423,40 @@ func (hr *Repo) getIndexByURL() (*repo.IndexFile, error) {
 	}
 
 	if hr.Auth != nil {
-		if hr.Auth.User != "" && hr.Auth.Password != "" {
-			req.SetBasicAuth(hr.Auth.User, hr.Auth.Password)
-		}
+		utils.SetRequestAuth(req, hr.Auth.User, hr.Auth.Password, hr.Auth.Token, hr.Auth.Headers)
+	}
+
+	if hr.ETag != "" {
+		req.Header.Set("If-None-Match", hr.ETag)
+	}
+
+	if hr.LastModified != "" {
+		req.Header.Set("If-Modified-Since", hr.LastModified)
 	}
 
 	if res, err = hr.getter.Do(req); err != nil {
 		return obj, err
 	}
 
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotModified {
+		hr.NotModified = true
+		return obj, nil
+	}
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return obj, errors.Errorf("unexpected status %v on downloading index of %v", res.Status, hr.Name)
+	}
+
 	if raw, err = io.ReadAll(res.Body); err != nil {
 		return obj, err
 	}
 
+	hr.ETag = res.Header.Get("ETag")
+	hr.LastModified = res.Header.Get("Last-Modified")
+	hr.Digest = fmt.Sprintf("sha256:%x", sha256.Sum256(raw))
+
 	if err := yaml.UnmarshalStrict(raw, &obj); err != nil {
 		hr.logger.Error(err, "error on unmarshaling http body to index file")
 	}
@@ -278,19 +464,47 @@ func (hr *Repo) getIndexByURL() (*repo.IndexFile, error) {
 	return obj, nil
 }
 
+func (hr *Repo) setTLSConfig(insecureSkipTLSVerify bool) error {
+	var ca, cert, key string
+
+	if hr.Auth != nil {
+		ca = hr.Auth.Ca
+		cert = hr.Auth.Cert
+		key = hr.Auth.Key
+	}
+
+	tlsConfig, err := utils.NewTLSConfig(ca, cert, key, insecureSkipTLSVerify)
+
+	if err != nil {
+		return err
+	}
+
+	hr.tlsConfig = tlsConfig
+	hr.getter = utils.NewTLSHTTPClient(hr.getter, tlsConfig)
+	return nil
+}
+
 func (hr *Repo) getEntryObj() (*repo.Entry, error) {
 	obj := &repo.Entry{
-		Name: hr.Name,
-		URL:  hr.URL,
+		Name:               hr.Name,
+		URL:                hr.URL,
+		PassCredentialsAll: hr.passCredentialsAll,
 	}
 
 	if hr.Auth != nil {
-		obj.CAFile = hr.Auth.Ca
-		obj.CertFile = hr.Auth.Cert
-		obj.KeyFile = hr.Auth.Key
 		obj.Username = hr.Auth.User
 		obj.Password = hr.Auth.Password
 	}
 
 	return obj, nil
 }
+
+func getIndexDigest(obj *repo.IndexFile) (string, error) {
+	raw, err := json.Marshal(obj.Entries)
+
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(raw)), nil
+}
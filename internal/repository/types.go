@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"crypto/tls"
 	"sync"
 
 	"github.com/go-logr/logr"
@@ -20,19 +21,31 @@ type Repos struct {
 
 // Repo represents struct for data needed for managing repos and list of installed
 type Repo struct {
-	Name       string
-	URL        string
-	Auth       *Auth
-	Namespace  Namespace
-	Settings   *cli.EnvSettings
-	K8sClient  client.Client
-	getter     utils.HTTPClientInterface
-	helmClient kube.Client
-	index      *repo.IndexFile
-	logger     logr.Logger
-	wg         *sync.WaitGroup
-	mu         sync.Mutex
-	ctx        context.Context
+	Name      string
+	URL       string
+	Auth      *Auth
+	Namespace Namespace
+	Settings  *cli.EnvSettings
+	K8sClient client.Client
+	// Digest, ETag and LastModified describe the fetched index
+	Digest       string
+	ETag         string
+	LastModified string
+	// NotModified is true if the index has not changed since the last sync
+	NotModified bool
+	// SkippedCharts maps charts of the index which are not synced to the reason
+	SkippedCharts map[string]string
+	getter        utils.HTTPClientInterface
+	helmClient    kube.Client
+	tlsConfig     *tls.Config
+	index         *repo.IndexFile
+	indexErr      error
+	// passCredentialsAll is true if credentials are also passed to chart urls on other hosts
+	passCredentialsAll bool
+	logger             logr.Logger
+	wg                 *sync.WaitGroup
+	mu                 sync.Mutex
+	ctx                context.Context
 }
 
 // Auth represents struct with auth data for a repo
@@ -42,6 +55,13 @@ type Auth struct {
 	Cert     string
 	Key      string
 	Ca       string
+	// Token is used as bearer token for http repositories or as https token for git repositories
+	Token string
+	// Headers are added to each http request to the repository
+	Headers map[string]string
+	// SSHKey and KnownHosts are only used for git repositories
+	SSHKey     string
+	KnownHosts string
 }
 
 // Namespace represents struct with release namespace name and if it should be installed
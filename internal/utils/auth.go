@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ParseAuthHeaders returns the headers of a secret value with one "Name: value" pair per line
+func ParseAuthHeaders(raw string) map[string]string {
+	headers := map[string]string{}
+
+	for _, line := range strings.Split(raw, "\n") {
+		name, value, ok := strings.Cut(line, ":")
+
+		if !ok || strings.TrimSpace(name) == "" {
+			continue
+		}
+
+		headers[http.CanonicalHeaderKey(strings.TrimSpace(name))] = strings.TrimSpace(value)
+	}
+
+	return headers
+}
+
+// SetRequestAuth adds basic auth, a bearer token if no basic auth is set and custom headers to the request
+func SetRequestAuth(req *http.Request, user, password, token string, headers map[string]string) {
+
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	if user != "" && password != "" {
+		req.SetBasicAuth(user, password)
+		return
+	}
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// IsSameHost returns true if both urls have the same scheme, host and port
+func IsSameHost(a, b string) bool {
+	ua, err := url.Parse(a)
+
+	if err != nil {
+		return false
+	}
+
+	ub, err := url.Parse(b)
+
+	if err != nil {
+		return false
+	}
+
+	return ua.Scheme == ub.Scheme && ua.Host == ub.Host
+}
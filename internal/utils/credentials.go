@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	// CredentialsSecret uses the token of a service account token secret
+	CredentialsSecret = "secret"
+	// CredentialsImpersonate uses the credentials of the agent impersonating the service account
+	CredentialsImpersonate = "impersonate"
+	// CredentialsTokenRequest uses short-lived tokens requested for the service account
+	CredentialsTokenRequest = "tokenRequest"
+)
+
+// serviceAccountTokenExpiration is the requested lifetime of service account tokens in seconds
+const serviceAccountTokenExpiration = 3600
+
+type cachedToken struct {
+	token      string
+	expiration time.Time
+}
+
+var tokenCache = struct {
+	sync.Mutex
+	tokens map[string]cachedToken
+}{tokens: map[string]cachedToken{}}
+
+// GetServiceAccountToken returns a short-lived token of the service account.
+// Tokens are cached and requested again if less than half of their lifetime is left.
+func GetServiceAccountToken(clientset kubernetes.Interface, namespace, name string) (string, error) {
+	key := namespace + "/" + name
+
+	tokenCache.Lock()
+	defer tokenCache.Unlock()
+
+	if cached, ok := tokenCache.tokens[key]; ok && time.Until(cached.expiration) > serviceAccountTokenExpiration*time.Second/2 {
+		return cached.token, nil
+	}
+
+	expiration := int64(serviceAccountTokenExpiration)
+	request := &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			ExpirationSeconds: &expiration,
+		},
+	}
+
+	tr, err := clientset.CoreV1().ServiceAccounts(namespace).CreateToken(context.Background(), name, request, metav1.CreateOptions{})
+
+	if err != nil {
+		return "", fmt.Errorf("failed to request token for service account %v: %w", key, err)
+	}
+
+	if len(tr.Status.Token) == 0 {
+		return "", fmt.Errorf("failed to request token for service account %v: no token in server response", key)
+	}
+
+	tokenCache.tokens[key] = cachedToken{
+		token:      tr.Status.Token,
+		expiration: tr.Status.ExpirationTimestamp.Time,
+	}
+
+	return tr.Status.Token, nil
+}
+
+// NewTokenKubeconfig returns a kubeconfig which authenticates with the token
+func NewTokenKubeconfig(server string, ca []byte, token, namespace string) *clientcmdapi.Config {
+	return newKubeconfig(&clientcmdapi.Cluster{
+		Server:                   server,
+		CertificateAuthorityData: ca,
+	}, &clientcmdapi.AuthInfo{
+		Token: token,
+	}, namespace)
+}
+
+// NewImpersonationKubeconfig returns a kubeconfig with the credentials of the rest config which impersonates the service account
+func NewImpersonationKubeconfig(rc *rest.Config, namespace, serviceAccount, releaseNamespace string) (*clientcmdapi.Config, error) {
+
+	if err := rest.LoadTLSFiles(rc); err != nil {
+		return nil, err
+	}
+
+	authInfo := &clientcmdapi.AuthInfo{
+		ClientCertificateData: rc.CertData,
+		ClientKeyData:         rc.KeyData,
+		TokenFile:             rc.BearerTokenFile,
+		Username:              rc.Username,
+		Password:              rc.Password,
+		AuthProvider:          rc.AuthProvider,
+		Exec:                  rc.ExecProvider,
+		Impersonate:           fmt.Sprintf("system:serviceaccount:%v:%v", namespace, serviceAccount),
+	}
+
+	// a token file is preferred because it is rotated
+	if rc.BearerTokenFile == "" {
+		authInfo.Token = rc.BearerToken
+	}
+
+	return newKubeconfig(&clientcmdapi.Cluster{
+		Server:                   rc.Host,
+		CertificateAuthorityData: rc.CAData,
+		InsecureSkipTLSVerify:    rc.Insecure,
+		TLSServerName:            rc.ServerName,
+	}, authInfo, releaseNamespace), nil
+}
+
+func newKubeconfig(cluster *clientcmdapi.Cluster, authInfo *clientcmdapi.AuthInfo, namespace string) *clientcmdapi.Config {
+	return &clientcmdapi.Config{
+		Kind:       "Config",
+		APIVersion: "v1",
+		Clusters: map[string]*clientcmdapi.Cluster{
+			"default-cluster": cluster,
+		},
+		Contexts: map[string]*clientcmdapi.Context{
+			"default-context": {
+				Cluster:   "default-cluster",
+				Namespace: namespace,
+				AuthInfo:  "default-user",
+			},
+		},
+		CurrentContext: "default-context",
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{
+			"default-user": authInfo,
+		},
+	}
+}
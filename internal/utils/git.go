@@ -0,0 +1,238 @@
+package utils
+
+import (
+	b64 "encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// GitRevisionAnnotation is set on the index entries of git repositories to the commit the chart was found in
+const GitRevisionAnnotation = "yaho.soer3n.dev/git-revision"
+
+// GitAuth represents credentials for fetching a git repository
+type GitAuth struct {
+	User       string
+	Token      string
+	SSHKey     string
+	KnownHosts string
+}
+
+// checkouts are shared between repository and chart controllers so they have to be serialized
+var gitMu sync.Mutex
+
+// WithGitCheckout fetches the configured revision of a git repository into a local working copy and calls f with its directory and commit
+func WithGitCheckout(name, repoURL string, source *helmv1alpha1.GitSource, auth *GitAuth, f func(dir, revision string) error) error {
+
+	if source == nil {
+		return errors.New("no git source configured")
+	}
+
+	gitMu.Lock()
+	defer gitMu.Unlock()
+
+	base := filepath.Join(os.TempDir(), "yaho", "git")
+	dir := filepath.Join(base, name)
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	env, err := getGitEnv(base, name, repoURL, auth)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, ".git")); os.IsNotExist(err) {
+		if _, err := runGit(dir, env, "init", "-q"); err != nil {
+			return err
+		}
+	}
+
+	// url is updated on each run as it could be changed in the repository resource
+	_, _ = runGit(dir, env, "remote", "remove", "origin")
+
+	if _, err := runGit(dir, env, "remote", "add", "origin", repoURL); err != nil {
+		return err
+	}
+
+	if _, err := runGit(dir, env, "fetch", "-q", "--depth", "1", "--no-tags", "origin", GetGitRef(source)); err != nil {
+		return err
+	}
+
+	if _, err := runGit(dir, env, "checkout", "-q", "--force", "FETCH_HEAD"); err != nil {
+		return err
+	}
+
+	if _, err := runGit(dir, env, "clean", "-q", "-ffdx"); err != nil {
+		return err
+	}
+
+	revision, err := runGit(dir, env, "rev-parse", "HEAD")
+
+	if err != nil {
+		return err
+	}
+
+	return f(dir, revision)
+}
+
+// GetGitRef returns the ref which should be fetched for a git source
+func GetGitRef(source *helmv1alpha1.GitSource) string {
+
+	if source.Commit != "" {
+		return source.Commit
+	}
+
+	if source.Tag != "" {
+		return "refs/tags/" + source.Tag
+	}
+
+	if source.Branch != "" {
+		return "refs/heads/" + source.Branch
+	}
+
+	return "HEAD"
+}
+
+// JoinGitPath joins a path of the repository with the directory of the working copy and rejects paths outside of it
+func JoinGitPath(dir, path string) (string, error) {
+	joined := filepath.Join(dir, path)
+
+	if joined != dir && !strings.HasPrefix(joined, dir+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %v is outside of git repository", path)
+	}
+
+	return joined, nil
+}
+
+// FindGitCharts returns the directories of all charts found below root
+func FindGitCharts(root string) ([]string, error) {
+	dirs := []string{}
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() {
+			return nil
+		}
+
+		if d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+
+		// subcharts are part of the parent chart
+		if ok, _ := chartutil.IsChartDir(path); ok {
+			dirs = append(dirs, path)
+			return filepath.SkipDir
+		}
+
+		return nil
+	})
+
+	return dirs, err
+}
+
+// IsGitSSHURL returns whether a git repository url uses ssh, either as ssh:// url or in scp-like syntax like git@example.com:org/repo.git
+func IsGitSSHURL(repoURL string) bool {
+
+	if strings.HasPrefix(repoURL, "ssh://") || strings.HasPrefix(repoURL, "git+ssh://") {
+		return true
+	}
+
+	if strings.Contains(repoURL, "://") {
+		return false
+	}
+
+	colon := strings.Index(repoURL, ":")
+	return colon > 0 && !strings.Contains(repoURL[:colon], "/")
+}
+
+func getGitEnv(base, name, repoURL string, auth *GitAuth) ([]string, error) {
+	env := []string{
+		"GIT_TERMINAL_PROMPT=0",
+		"HOME=" + base,
+	}
+
+	if IsGitSSHURL(repoURL) {
+		sshCommand, err := getGitSSHCommand(base, name, auth)
+
+		if err != nil {
+			return env, err
+		}
+
+		env = append(env, "GIT_SSH_COMMAND="+sshCommand)
+	}
+
+	if auth == nil {
+		return env, nil
+	}
+
+	// token is passed as config via environment so that it is not visible in process arguments
+	if auth.Token != "" {
+		user := auth.User
+
+		if user == "" {
+			user = "git"
+		}
+
+		header := "Authorization: Basic " + b64.StdEncoding.EncodeToString([]byte(user+":"+auth.Token))
+		env = append(env, "GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=http.extraHeader", "GIT_CONFIG_VALUE_0="+header)
+	}
+
+	return env, nil
+}
+
+// getGitSSHCommand returns the ssh command which only accepts the host keys of the known hosts of the auth
+func getGitSSHCommand(base, name string, auth *GitAuth) (string, error) {
+
+	// without known hosts the first connection could be intercepted
+	if auth == nil || auth.KnownHosts == "" {
+		return "", errors.New("knownHosts is required for git repositories with ssh urls")
+	}
+
+	knownHostsFile := filepath.Join(base, name+".known_hosts")
+
+	if err := os.WriteFile(knownHostsFile, []byte(auth.KnownHosts), 0o600); err != nil {
+		return "", err
+	}
+
+	command := fmt.Sprintf("ssh -o UserKnownHostsFile=%s -o StrictHostKeyChecking=yes -o BatchMode=yes", knownHostsFile)
+
+	if auth.SSHKey == "" {
+		return command, nil
+	}
+
+	keyFile := filepath.Join(base, name+".key")
+
+	if err := os.WriteFile(keyFile, []byte(strings.TrimSuffix(auth.SSHKey, "\n")+"\n"), 0o600); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s -i %s -o IdentitiesOnly=yes", command, keyFile), nil
+}
+
+func runGit(dir string, env []string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+
+	out, err := cmd.CombinedOutput()
+
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %s", args[0], strings.TrimSpace(string(out)))
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
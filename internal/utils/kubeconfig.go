@@ -0,0 +1,189 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// KubeconfigSecretKey is the default key of a kubeconfig in a cluster secret
+const KubeconfigSecretKey = "kubeconfig"
+
+// ParseKubeconfig loads a kubeconfig of a secret and reduces it to the given or the current context.
+// As it is loaded by the agent only embedded credentials are accepted, see ValidateKubeconfigCredentials.
+func ParseKubeconfig(raw []byte, context string) (*clientcmdapi.Config, error) {
+	config, err := clientcmd.Load(raw)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := reduceKubeconfig(config, context); err != nil {
+		return nil, err
+	}
+
+	if err := ValidateKubeconfigCredentials(config); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// PrepareKubeconfig validates a local kubeconfig and reduces it to the given or the current context.
+// Referenced certificate, key and token files are embedded.
+func PrepareKubeconfig(config *clientcmdapi.Config, context string) error {
+
+	if err := reduceKubeconfig(config, context); err != nil {
+		return err
+	}
+
+	if err := clientcmdapi.FlattenConfig(config); err != nil {
+		return err
+	}
+
+	for _, authInfo := range config.AuthInfos {
+		if authInfo.TokenFile == "" {
+			continue
+		}
+
+		token, err := os.ReadFile(authInfo.TokenFile)
+
+		if err != nil {
+			return err
+		}
+
+		authInfo.Token = strings.TrimSpace(string(token))
+		authInfo.TokenFile = ""
+	}
+
+	return ValidateKubeconfigCredentials(config)
+}
+
+// ValidateKubeconfigCredentials rejects credentials which run commands or read files on the host loading the kubeconfig.
+// Otherwise a kubeconfig of a secret could execute commands in the agent or use the token of its service account.
+// Only certificates, keys, tokens and basic auth embedded in the kubeconfig are accepted.
+func ValidateKubeconfigCredentials(config *clientcmdapi.Config) error {
+
+	for name, cluster := range config.Clusters {
+		if cluster.CertificateAuthority != "" {
+			return fmt.Errorf("cluster %v references file %v, only certificate-authority-data is supported", name, cluster.CertificateAuthority)
+		}
+	}
+
+	for name, authInfo := range config.AuthInfos {
+		switch {
+		case authInfo.Exec != nil:
+			return fmt.Errorf("user %v uses an exec plugin, which is not supported", name)
+		case authInfo.AuthProvider != nil:
+			return fmt.Errorf("user %v uses an auth provider, which is not supported", name)
+		case authInfo.TokenFile != "":
+			return fmt.Errorf("user %v references token file %v, only token is supported", name, authInfo.TokenFile)
+		case authInfo.ClientCertificate != "":
+			return fmt.Errorf("user %v references file %v, only client-certificate-data is supported", name, authInfo.ClientCertificate)
+		case authInfo.ClientKey != "":
+			return fmt.Errorf("user %v references file %v, only client-key-data is supported", name, authInfo.ClientKey)
+		}
+	}
+
+	return nil
+}
+
+// reduceKubeconfig validates a kubeconfig and removes everything not needed by the given or the current context
+func reduceKubeconfig(config *clientcmdapi.Config, context string) error {
+
+	if context != "" {
+		config.CurrentContext = context
+	}
+
+	if config.CurrentContext == "" {
+		return errors.New("kubeconfig has no current context")
+	}
+
+	if err := clientcmd.Validate(*config); err != nil {
+		return err
+	}
+
+	return clientcmdapi.MinifyConfig(config)
+}
+
+// GetKubeconfigServer returns the api server of the current context
+func GetKubeconfigServer(config *clientcmdapi.Config) string {
+	kubeContext, ok := config.Contexts[config.CurrentContext]
+
+	if !ok {
+		return ""
+	}
+
+	if cluster, ok := config.Clusters[kubeContext.Cluster]; ok {
+		return cluster.Server
+	}
+
+	return ""
+}
+
+// GetClusterKubeconfig returns the kubeconfig of the referenced secret with the release namespace as default namespace
+func GetClusterKubeconfig(c client.Client, namespace, releaseNamespace string, cluster *helmv1alpha1.ClusterReference) (*clientcmdapi.Config, error) {
+	secret := &v1.Secret{}
+
+	if err := c.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: cluster.KubeconfigSecret}, secret); err != nil {
+		return nil, err
+	}
+
+	key := cluster.Key
+
+	if key == "" {
+		key = KubeconfigSecretKey
+	}
+
+	raw, ok := secret.Data[key]
+
+	if !ok {
+		return nil, fmt.Errorf("no %v found in secret %v", key, cluster.KubeconfigSecret)
+	}
+
+	config, err := ParseKubeconfig(raw, cluster.Context)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig in secret %v: %w", cluster.KubeconfigSecret, err)
+	}
+
+	config.Contexts[config.CurrentContext].Namespace = releaseNamespace
+	return config, nil
+}
+
+// NewKubeconfigSecret returns a secret which can be referenced by releases for deploying to the cluster of the kubeconfig
+func NewKubeconfigSecret(name, namespace string, config *clientcmdapi.Config) (*v1.Secret, error) {
+	raw, err := clientcmd.Write(*config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"yaho.soer3n.dev/type": "kubeconfig",
+			},
+		},
+		Type: v1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			KubeconfigSecretKey: raw,
+		},
+	}, nil
+}
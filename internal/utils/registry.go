@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"crypto/tls"
+	b64 "encoding/base64"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+// NewRegistryClient returns a helm registry client for an oci repository which uses the given credentials
+func NewRegistryClient(repoURL, user, password string, plainHTTP bool, tlsConfig *tls.Config) (*registry.Client, error) {
+
+	dir := filepath.Join(os.TempDir(), "yaho", "registry")
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	// credentials are passed in a credentials file to avoid a login request to the registry. The file is only read
+	// on creating the client, so each client gets its own file which is removed afterwards and can't be shared
+	// between repositories or concurrent reconciles.
+	credentialsFile, err := os.CreateTemp(dir, "credentials-*.json")
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.Remove(credentialsFile.Name())
+
+	auths := map[string]map[string]map[string]string{
+		"auths": {},
+	}
+
+	if user != "" && password != "" {
+		auths["auths"][GetRegistryHost(repoURL)] = map[string]string{
+			"auth": b64.StdEncoding.EncodeToString([]byte(user + ":" + password)),
+		}
+	}
+
+	if err := json.NewEncoder(credentialsFile).Encode(auths); err != nil {
+		credentialsFile.Close()
+		return nil, err
+	}
+
+	if err := credentialsFile.Close(); err != nil {
+		return nil, err
+	}
+
+	opts := []registry.ClientOption{
+		registry.ClientOptCredentialsFile(credentialsFile.Name()),
+	}
+
+	if plainHTTP {
+		opts = append(opts, registry.ClientOptPlainHTTP())
+	}
+
+	if tlsConfig != nil {
+		opts = append(opts, registry.ClientOptHTTPClient(NewTLSHTTPClient(&http.Client{}, tlsConfig).(*http.Client)))
+	}
+
+	return registry.NewClient(opts...)
+}
+
+// GetRegistryHost returns the host part of an oci repository url
+func GetRegistryHost(repoURL string) string {
+	ref := strings.TrimPrefix(repoURL, registry.OCIScheme+"://")
+	return strings.SplitN(ref, "/", 2)[0]
+}
+
+// GetRegistryRef returns the reference of a chart in an oci repository without scheme
+func GetRegistryRef(repoURL, chart string) string {
+	ref := strings.TrimPrefix(repoURL, registry.OCIScheme+"://")
+	return strings.TrimSuffix(ref, "/") + "/" + chart
+}
@@ -4,24 +4,22 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
-	authenticationv1 "k8s.io/api/authentication/v1"
 	v1 "k8s.io/api/core/v1"
 
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/discovery/cached/memory"
-	sa "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
-	clientcmdlatest "k8s.io/client-go/tools/clientcmd/api/latest"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	conf "sigs.k8s.io/controller-runtime/pkg/client/config"
@@ -30,12 +28,13 @@ import (
 	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
 )
 
-func NewRESTClientGetter(config *helmv1alpha1.Config, namespace, releaseNamespace string, isLocal bool, c client.Client, logger logr.Logger) (*HelmRESTClientGetter, error) {
+func NewRESTClientGetter(config *helmv1alpha1.Config, cluster *helmv1alpha1.ClusterReference, namespace, releaseNamespace string, isLocal bool, c client.Client, logger logr.Logger) (*HelmRESTClientGetter, error) {
 
 	getter := &HelmRESTClientGetter{
 		Namespace:        namespace,
 		ReleaseNamespace: releaseNamespace,
 		HelmConfig:       config,
+		Cluster:          cluster,
 		Client:           c,
 		logger:           logger,
 		IsLocal:          isLocal,
@@ -50,23 +49,85 @@ func NewRESTClientGetter(config *helmv1alpha1.Config, namespace, releaseNamespac
 
 func (h *HelmRESTClientGetter) setKubeconfig() error {
 
-	clienCmdConfig := h.ToRawKubeConfigLoader()
-	clientConfig, err := clienCmdConfig.RawConfig()
+	config, err := h.buildKubeconfig()
 
 	if err != nil {
 		return err
 	}
 
-	rawConfig, err := runtime.Encode(clientcmdlatest.Codec, &clientConfig)
+	rawConfig, err := clientcmd.Write(*config)
 
 	if err != nil {
 		return err
 	}
 
 	h.KubeConfig = string(rawConfig)
-
+	h.Server = GetKubeconfigServer(config)
 	return nil
+}
+
+// buildKubeconfig returns the kubeconfig of the remote cluster or of the service account of the config
+func (h *HelmRESTClientGetter) buildKubeconfig() (*clientcmdapi.Config, error) {
+
+	if h.Cluster != nil {
+		return GetClusterKubeconfig(h.Client, h.Namespace, h.ReleaseNamespace, h.Cluster)
+	}
+
+	serviceAccountName := "default"
+	credentials := CredentialsSecret
+
+	if h.HelmConfig != nil {
+		serviceAccountName = h.HelmConfig.Spec.ServiceAccountName
+
+		if h.HelmConfig.Spec.Credentials != "" {
+			credentials = h.HelmConfig.Spec.Credentials
+		}
+	}
+
+	if credentials == CredentialsSecret {
+		token, ca, err := h.getSecretToken(serviceAccountName)
+
+		if err != nil {
+			return nil, err
+		}
+
+		server := "https://kubernetes.svc.default.cluster.local"
+
+		// for testing purposes rewrite cluster apiserver address
+		if h.IsLocal {
+			server = "https://127.0.0.1:6443"
+		}
+
+		return NewTokenKubeconfig(server, ca, string(token), h.ReleaseNamespace), nil
+	}
+
+	rc, err := conf.GetConfig()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if credentials == CredentialsImpersonate {
+		return NewImpersonationKubeconfig(rc, h.Namespace, serviceAccountName, h.ReleaseNamespace)
+	}
 
+	clientset, err := kubernetes.NewForConfig(rc)
+
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := GetServiceAccountToken(clientset, h.Namespace, serviceAccountName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rest.LoadTLSFiles(rc); err != nil {
+		return nil, err
+	}
+
+	return NewTokenKubeconfig(rc.Host, rc.CAData, token, h.ReleaseNamespace), nil
 }
 
 func (c *HelmRESTClientGetter) ToRESTConfig() (*rest.Config, error) {
@@ -112,39 +173,52 @@ func (c *HelmRESTClientGetter) ToRESTMapper() (meta.RESTMapper, error) {
 	return expander, nil
 }
 
-func (c *HelmRESTClientGetter) ToRawKubeConfigLoader() clientcmd.ClientConfig {
+// IsReachable returns an error if the api server of the kubeconfig does not respond
+func (c *HelmRESTClientGetter) IsReachable() error {
+	config, err := c.ToRESTConfig()
 
-	serviceAccount := &v1.ServiceAccount{}
-	serviceAccountName := "default"
+	if err != nil {
+		return err
+	}
 
-	if c.HelmConfig != nil {
-		serviceAccountName = c.HelmConfig.Spec.ServiceAccountName
+	config.Timeout = 10 * time.Second
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+
+	if err != nil {
+		return err
 	}
 
-	if err := c.Client.Get(context.Background(), types.NamespacedName{Namespace: c.Namespace, Name: serviceAccountName}, serviceAccount); err != nil {
-		fmt.Printf("error on getting service account. msg: %v", err.Error())
+	_, err = discoveryClient.ServerVersion()
+	return err
+}
+
+func (c *HelmRESTClientGetter) ToRawKubeConfigLoader() clientcmd.ClientConfig {
+
+	// the kubeconfig is built on initialization of the getter
+	returnClient, err := clientcmd.NewClientConfigFromBytes([]byte(c.KubeConfig))
+
+	if err != nil {
+		c.logger.Info(err.Error(), "key", "loader")
 		return nil
 	}
 
-	var secret *v1.Secret
+	return returnClient
+}
 
-	// for kubernetes >= 1.24 we need to create and connect the secret token by ourself
-	if serviceAccount.Secrets == nil {
-		rc, err := conf.GetConfig()
+// getSecretToken returns token and ca of a service account token secret which is created if the service account has none
+func (c *HelmRESTClientGetter) getSecretToken(serviceAccountName string) ([]byte, []byte, error) {
 
-		if err != nil {
-			fmt.Println(err.Error())
-			return nil
-		}
+	serviceAccount := &v1.ServiceAccount{}
 
-		sac, err := sa.NewForConfig(rc)
+	if err := c.Client.Get(context.Background(), types.NamespacedName{Namespace: c.Namespace, Name: serviceAccountName}, serviceAccount); err != nil {
+		return nil, nil, fmt.Errorf("error on getting service account: %w", err)
+	}
 
-		if err != nil {
-			fmt.Println(err.Error())
-			return nil
-		}
+	secret := &v1.Secret{}
 
-		tokenSecret := &v1.Secret{
+	// for kubernetes >= 1.24 we need to create and connect the secret token by ourself
+	if serviceAccount.Secrets == nil {
+		secret = &v1.Secret{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      "account-secret-" + serviceAccountName,
 				Namespace: c.Namespace,
@@ -155,110 +229,35 @@ func (c *HelmRESTClientGetter) ToRawKubeConfigLoader() clientcmd.ClientConfig {
 			Type: v1.SecretTypeServiceAccountToken,
 		}
 
-		err = c.Client.Get(context.Background(), types.NamespacedName{Namespace: c.Namespace, Name: tokenSecret.Name}, tokenSecret)
-
-		if err != nil {
-			fmt.Printf("error on getting token secret. msg: %v\n", err.Error())
-		}
+		err := c.Client.Get(context.Background(), types.NamespacedName{Namespace: c.Namespace, Name: secret.Name}, secret)
 
 		if k8serrors.IsNotFound(err) {
+			c.logger.Info("create token secret", "name", secret.Name)
 
-			fmt.Println("create secret...")
-
-			if err := c.Client.Create(context.Background(), tokenSecret); err != nil {
-				fmt.Printf("error on creating token secret. msg: %v\n", err.Error())
-				return nil
-			}
-
-			request := &authenticationv1.TokenRequest{
-				Spec: authenticationv1.TokenRequestSpec{
-					Audiences: []string{},
-					BoundObjectRef: &authenticationv1.BoundObjectReference{
-						Kind:       "Secret",
-						APIVersion: "v1",
-						Name:       "account-secret-" + serviceAccountName,
-					},
-				},
-			}
-
-			fmt.Println("create token...")
-
-			tr, err := sac.ServiceAccounts(c.Namespace).CreateToken(context.TODO(), serviceAccountName, request, metav1.CreateOptions{})
-
-			if err != nil {
-				fmt.Printf("failed to create token: %v\n", err)
+			if err := c.Client.Create(context.Background(), secret); err != nil {
+				return nil, nil, fmt.Errorf("error on creating token secret: %w", err)
 			}
-			if len(tr.Status.Token) == 0 {
-				fmt.Println("failed to create token: no token in server response")
-			}
-
-			fmt.Println("get updated secret...")
 
-			if err := c.Client.Get(context.Background(), types.NamespacedName{Namespace: c.Namespace, Name: tokenSecret.Name}, tokenSecret); err != nil {
-				fmt.Printf("error on getting token secret. msg: %v\n", err.Error())
-			}
+			// the token is set by the token controller after the secret was created
+			return nil, nil, fmt.Errorf("token secret %v created and not populated yet", secret.Name)
 		}
 
-		secret = tokenSecret
-	}
-
-	if secret == nil {
-		tokenSecret := &v1.Secret{}
-		for _, s := range serviceAccount.Secrets {
-			if err := c.Client.Get(context.Background(), types.NamespacedName{Namespace: c.Namespace, Name: s.Name}, tokenSecret); err != nil {
-				fmt.Printf("error on getting token secret. msg: %v", err.Error())
-				continue
-			}
-			break
+		if err != nil {
+			return nil, nil, fmt.Errorf("error on getting token secret: %w", err)
 		}
-
-		secret = tokenSecret
-	}
-
-	rawToken := secret.Data["token"]
-
-	clusters := make(map[string]*clientcmdapi.Cluster)
-	clusters["default-cluster"] = &clientcmdapi.Cluster{
-		Server:                   "https://kubernetes.svc.default.cluster.local",
-		CertificateAuthorityData: secret.Data["ca.crt"],
 	}
 
-	// for testing purposes rewrite cluster apiserver address
-	if c.IsLocal {
-		clusters["default-cluster"].Server = "https://127.0.0.1:6443"
-	}
-
-	contexts := make(map[string]*clientcmdapi.Context)
-	contexts["default-context"] = &clientcmdapi.Context{
-		Cluster:   "default-cluster",
-		Namespace: c.ReleaseNamespace,
-		AuthInfo:  c.ReleaseNamespace,
-	}
-
-	authinfos := make(map[string]*clientcmdapi.AuthInfo)
-	authinfos[c.ReleaseNamespace] = &clientcmdapi.AuthInfo{
-		Token: string(rawToken),
+	for _, s := range serviceAccount.Secrets {
+		if err := c.Client.Get(context.Background(), types.NamespacedName{Namespace: c.Namespace, Name: s.Name}, secret); err != nil {
+			c.logger.Info("error on getting token secret", "name", s.Name, "error", err.Error())
+			continue
+		}
+		break
 	}
 
-	clientConfig := clientcmdapi.Config{
-		Kind:           "Config",
-		APIVersion:     "v1",
-		Clusters:       clusters,
-		Contexts:       contexts,
-		CurrentContext: "default-context",
-		AuthInfos:      authinfos,
+	if len(secret.Data["token"]) == 0 {
+		return nil, nil, fmt.Errorf("no token found for service account %v", serviceAccountName)
 	}
 
-	overrides := &clientcmd.ConfigOverrides{ClusterDefaults: clientcmd.ClusterDefaults}
-	overrides.Context.Namespace = c.ReleaseNamespace
-
-	rawConfig, err := runtime.Encode(clientcmdlatest.Codec, &clientConfig)
-
-	if err != nil {
-		return nil
-	}
-
-	returnClient, _ := clientcmd.NewClientConfigFromBytes(rawConfig)
-
-	return returnClient
+	return secret.Data["token"], secret.Data["ca.crt"], nil
 }
@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SuspendedCondition shows that the reconciliation of a resource is suspended
+const SuspendedCondition = "suspended"
+
+// SetSuspendedCondition sets the suspended condition with the message or removes it if the resource is not suspended.
+// It returns whether the conditions changed.
+func SetSuspendedCondition(conditions *[]metav1.Condition, suspended bool, message string) bool {
+
+	if !suspended {
+		return meta.RemoveStatusCondition(conditions, SuspendedCondition)
+	}
+
+	return meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:    SuspendedCondition,
+		Status:  metav1.ConditionTrue,
+		Reason:  "suspended",
+		Message: message,
+	})
+}
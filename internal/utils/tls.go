@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// GetTLSSecretData returns ca bundle, client certificate and key of a tls secret
+func GetTLSSecretData(c client.Client, name, namespace string) (string, string, string, error) {
+	secretObj := &v1.Secret{}
+
+	if err := c.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: name}, secretObj); err != nil {
+		return "", "", "", err
+	}
+
+	return string(secretObj.Data["ca.crt"]), string(secretObj.Data[v1.TLSCertKey]), string(secretObj.Data[v1.TLSPrivateKeyKey]), nil
+}
+
+// NewTLSConfig returns a tls config for the given pem encoded ca bundle and client certificate
+func NewTLSConfig(ca, cert, key string, insecureSkipTLSVerify bool) (*tls.Config, error) {
+
+	if ca == "" && cert == "" && key == "" && !insecureSkipTLSVerify {
+		return nil, nil
+	}
+
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: insecureSkipTLSVerify, // #nosec G402 explicitly requested by repository spec
+	}
+
+	if ca != "" {
+		pool, err := x509.SystemCertPool()
+
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM([]byte(ca)) {
+			return nil, errors.New("no valid certificates found in ca bundle")
+		}
+
+		config.RootCAs = pool
+	}
+
+	if cert != "" || key != "" {
+		certificate, err := tls.X509KeyPair([]byte(cert), []byte(key))
+
+		if err != nil {
+			return nil, err
+		}
+
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	return config, nil
+}
+
+// NewTLSHTTPClient returns a copy of the http client which uses the given tls config.
+// Clients which are not of type http.Client like mocks are returned unchanged.
+func NewTLSHTTPClient(g HTTPClientInterface, config *tls.Config) HTTPClientInterface {
+
+	c, ok := g.(*http.Client)
+
+	if !ok || config == nil {
+		return g
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config
+
+	copied := *c
+	copied.Transport = transport
+
+	return &copied
+}
@@ -27,8 +27,12 @@ type HelmRESTClientGetter struct {
 	KubeConfig       string
 	IsLocal          bool
 	HelmConfig       *helmv1alpha1.Config
-	Client           client.Client
-	logger           logr.Logger
+	// Cluster references the kubeconfig of a remote cluster which is used instead of the service account
+	Cluster *helmv1alpha1.ClusterReference
+	// Server is the api server of the remote cluster
+	Server string
+	Client client.Client
+	logger logr.Logger
 }
 
 // ClientInterface repesents interface for mocking custom k8s client
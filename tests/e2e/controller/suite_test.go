@@ -703,7 +703,7 @@ func CompareValues(name, namespace string, expected map[string]interface{}) erro
 		},
 	}
 
-	getter, _ := utils.NewRESTClientGetter(config, namespace, namespace, true, testClient, logf.Log)
+	getter, _ := utils.NewRESTClientGetter(config, nil, namespace, namespace, true, testClient, logf.Log)
 	ac, err := utils.InitActionConfig(getter, []byte{}, logf.Log)
 
 	if err != nil {
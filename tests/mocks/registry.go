@@ -0,0 +1,155 @@
+package mocks
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+// RegistryMock represents a minimal oci registry serving helm charts as a local stand-in for testing
+type RegistryMock struct {
+	*httptest.Server
+	manifests map[string][]byte
+	blobs     map[string][]byte
+	tags      map[string][]string
+	mu        sync.Mutex
+}
+
+// NewRegistryMock returns a started registry mock
+func NewRegistryMock() *RegistryMock {
+	r := &RegistryMock{
+		manifests: map[string][]byte{},
+		blobs:     map[string][]byte{},
+		tags:      map[string][]string{},
+	}
+
+	r.Server = httptest.NewServer(http.HandlerFunc(r.serve))
+	return r
+}
+
+// Host returns host and port of the registry mock
+func (r *RegistryMock) Host() string {
+	return strings.TrimPrefix(r.Server.URL, "http://")
+}
+
+// AddChart pushes the chart archive found at path into the given repository of the registry mock
+func (r *RegistryMock) AddChart(repository, path string) error {
+	raw, err := os.ReadFile(path)
+
+	if err != nil {
+		return err
+	}
+
+	c, err := loader.LoadFile(path)
+
+	if err != nil {
+		return err
+	}
+
+	config, err := json.Marshal(c.Metadata)
+
+	if err != nil {
+		return err
+	}
+
+	manifest, err := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"mediaType":     "application/vnd.oci.image.manifest.v1+json",
+		"config":        r.addBlob(registry.ConfigMediaType, config),
+		"layers": []map[string]interface{}{
+			r.addBlob(registry.ChartLayerMediaType, raw),
+		},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := repository + "/" + c.Metadata.Name
+	tag := strings.ReplaceAll(c.Metadata.Version, "+", "_")
+	r.manifests[name+":"+tag] = manifest
+	r.manifests[name+":"+digest(manifest)] = manifest
+	r.tags[name] = append(r.tags[name], tag)
+
+	return nil
+}
+
+func (r *RegistryMock) addBlob(mediaType string, data []byte) map[string]interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d := digest(data)
+	r.blobs[d] = data
+
+	return map[string]interface{}{
+		"mediaType": mediaType,
+		"digest":    d,
+		"size":      len(data),
+	}
+}
+
+func (r *RegistryMock) serve(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	path := strings.TrimPrefix(req.URL.Path, "/v2/")
+
+	switch {
+	case path == "" || path == req.URL.Path:
+		w.WriteHeader(http.StatusOK)
+	case strings.HasSuffix(path, "/tags/list"):
+		name := strings.TrimSuffix(path, "/tags/list")
+		raw, _ := json.Marshal(map[string]interface{}{"name": name, "tags": r.tags[name]})
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(raw)
+	case strings.Contains(path, "/manifests/"):
+		parts := strings.SplitN(path, "/manifests/", 2)
+		manifest, ok := r.manifests[parts[0]+":"+parts[1]]
+
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		r.write(w, req, "application/vnd.oci.image.manifest.v1+json", manifest)
+	case strings.Contains(path, "/blobs/"):
+		parts := strings.SplitN(path, "/blobs/", 2)
+		blob, ok := r.blobs[parts[1]]
+
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		r.write(w, req, "application/octet-stream", blob)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (r *RegistryMock) write(w http.ResponseWriter, req *http.Request, mediaType string, data []byte) {
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Set("Docker-Content-Digest", digest(data))
+	w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+
+	if req.Method == http.MethodHead {
+		return
+	}
+
+	_, _ = w.Write(data)
+}
+
+func digest(data []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+}
@@ -0,0 +1,93 @@
+package helm
+
+import (
+	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GetTestKubeconfig returns a kubeconfig with a remote and a local context
+func GetTestKubeconfig() []byte {
+	return []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: remote
+  cluster:
+    server: https://remote.example:6443
+    certificate-authority-data: ZHVtbXljYQ==
+- name: local
+  cluster:
+    server: https://127.0.0.1:6443
+contexts:
+- name: remote
+  context:
+    cluster: remote
+    user: remote-admin
+- name: local
+  context:
+    cluster: local
+    user: local-admin
+current-context: local
+users:
+- name: remote-admin
+  user:
+    token: remote-token
+- name: local-admin
+  user:
+    token: local-token
+`)
+}
+
+// GetTestUnsafeKubeconfigs returns kubeconfigs whose credentials run commands or read files on the host loading them
+func GetTestUnsafeKubeconfigs() map[string][]byte {
+	kubeconfig := func(cluster, user string) []byte {
+		return []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: remote
+  cluster:
+    server: https://remote.example:6443
+` + cluster + `contexts:
+- name: remote
+  context:
+    cluster: remote
+    user: remote-admin
+current-context: remote
+users:
+- name: remote-admin
+  user:
+` + user)
+	}
+
+	return map[string][]byte{
+		"exec":                  kubeconfig("", "    exec:\n      apiVersion: client.authentication.k8s.io/v1\n      command: /bin/sh\n      args: [\"-c\", \"id\"]\n      interactiveMode: Never\n"),
+		"auth provider":         kubeconfig("", "    auth-provider:\n      name: oidc\n      config:\n        cmd-path: /bin/sh\n"),
+		"token file":            kubeconfig("", "    tokenFile: /etc/hosts\n"),
+		"client certificate":    kubeconfig("", "    client-certificate: /etc/hosts\n    client-key-data: ZHVtbXlrZXk=\n"),
+		"client key":            kubeconfig("", "    client-certificate-data: ZHVtbXljZXJ0\n    client-key: /etc/hosts\n"),
+		"certificate authority": kubeconfig("    certificate-authority: /etc/hosts\n", "    token: remote-token\n"),
+	}
+}
+
+// GetTestKubeconfigSecret returns a secret with the test kubeconfig stored under the given key
+func GetTestKubeconfigSecret(key string) *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "remote-cluster",
+			Namespace: "foo",
+		},
+		Data: map[string][]byte{
+			key: GetTestKubeconfig(),
+		},
+	}
+}
+
+// GetTestClusterReference returns a reference to the remote context of the test kubeconfig secret
+func GetTestClusterReference() *helmv1alpha1.ClusterReference {
+	return &helmv1alpha1.ClusterReference{
+		Name:             "remote",
+		KubeconfigSecret: "remote-cluster",
+		Key:              "config",
+		Context:          "remote",
+	}
+}
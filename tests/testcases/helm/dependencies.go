@@ -0,0 +1,46 @@
+package helm
+
+import (
+	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// GetTestDependentRelease returns a release in the namespace helm which depends on the given releases
+func GetTestDependentRelease(name string, synced bool, dependsOn ...helmv1alpha1.ReleaseDependency) helmv1alpha1.Release {
+	status := metav1.ConditionFalse
+
+	if synced {
+		status = metav1.ConditionTrue
+	}
+
+	return helmv1alpha1.Release{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       name,
+			Namespace:  "helm",
+			Generation: 1,
+		},
+		Spec: helmv1alpha1.ReleaseSpec{
+			Name:      name,
+			DependsOn: dependsOn,
+		},
+		Status: helmv1alpha1.ReleaseStatus{
+			Conditions: []metav1.Condition{
+				{Type: "synced", Status: status, ObservedGeneration: 1},
+			},
+		},
+	}
+}
+
+// GetTestReleaseLookup returns a lookup of the given releases by namespace and name
+func GetTestReleaseLookup(releases ...helmv1alpha1.Release) func(key types.NamespacedName) (*helmv1alpha1.Release, error) {
+	return func(key types.NamespacedName) (*helmv1alpha1.Release, error) {
+		for i := range releases {
+			if releases[i].Namespace == key.Namespace && releases[i].Name == key.Name {
+				return &releases[i], nil
+			}
+		}
+
+		return nil, nil
+	}
+}
@@ -0,0 +1,140 @@
+package helm
+
+import (
+	inttypes "github.com/soer3n/yaho/tests/mocks/types"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// GetTestDriftSpecs returns desired and live objects with the expected drift reason
+func GetTestDriftSpecs() []inttypes.TestCase {
+	desired := map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":   "foo",
+			"labels": map[string]interface{}{"app": "foo"},
+		},
+		"spec": map[string]interface{}{
+			"replicas": int64(1),
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "foo", "image": "foo:1.0"},
+					},
+				},
+			},
+		},
+	}
+
+	return []inttypes.TestCase{
+		{
+			// defaults and status of the live object are ignored
+			Input: []map[string]interface{}{desired, getTestDriftLive(func(live map[string]interface{}) {
+				live["status"] = map[string]interface{}{"replicas": int64(1)}
+				live["metadata"].(map[string]interface{})["uid"] = "1234"
+				containers := live["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})["containers"].([]interface{})
+				containers[0].(map[string]interface{})["imagePullPolicy"] = "IfNotPresent"
+			}, desired)},
+			ReturnValue: "",
+		},
+		{
+			Input: []map[string]interface{}{desired, getTestDriftLive(func(live map[string]interface{}) {
+				live["spec"].(map[string]interface{})["replicas"] = float64(3)
+			}, desired)},
+			ReturnValue: "spec.replicas",
+		},
+		{
+			Input: []map[string]interface{}{desired, getTestDriftLive(func(live map[string]interface{}) {
+				containers := live["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})["containers"].([]interface{})
+				containers[0].(map[string]interface{})["image"] = "foo:2.0"
+			}, desired)},
+			ReturnValue: "spec.template.spec.containers[0].image",
+		},
+		{
+			Input: []map[string]interface{}{desired, getTestDriftLive(func(live map[string]interface{}) {
+				delete(live["metadata"].(map[string]interface{}), "labels")
+			}, desired)},
+			ReturnValue: "metadata.labels",
+		},
+		{
+			// stringData of secrets is stored encoded in data
+			Input:       []map[string]interface{}{getTestDriftSecret("stringData", "secret"), getTestDriftSecret("data", "c2VjcmV0")},
+			ReturnValue: "",
+		},
+		{
+			Input:       []map[string]interface{}{getTestDriftSecret("stringData", "changed"), getTestDriftSecret("data", "c2VjcmV0")},
+			ReturnValue: "data.password",
+		},
+		{
+			// quantities are stored in canonical form
+			Input:       []map[string]interface{}{getTestDriftResources("1000m", int64(2)), getTestDriftResources("1", "2")},
+			ReturnValue: "",
+		},
+		{
+			Input:       []map[string]interface{}{getTestDriftResources("500m", "2Gi"), getTestDriftResources("1", "2Gi")},
+			ReturnValue: "spec.containers[0].resources.limits.cpu",
+		},
+		{
+			// only resource quantities are compared by their value
+			Input: []map[string]interface{}{getTestDriftLive(func(obj map[string]interface{}) {
+				obj["metadata"].(map[string]interface{})["labels"] = map[string]interface{}{"size": "1000m"}
+			}, desired), getTestDriftLive(func(live map[string]interface{}) {
+				live["metadata"].(map[string]interface{})["labels"] = map[string]interface{}{"size": "1"}
+			}, desired)},
+			ReturnValue: "metadata.labels.size",
+		},
+		{
+			// empty values of the manifest are omitted in the live object
+			Input: []map[string]interface{}{getTestDriftLive(func(obj map[string]interface{}) {
+				obj["metadata"].(map[string]interface{})["annotations"] = map[string]interface{}{}
+				containers := obj["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})["containers"].([]interface{})
+				containers[0].(map[string]interface{})["args"] = []interface{}{}
+				containers[0].(map[string]interface{})["workingDir"] = ""
+			}, desired), desired},
+			ReturnValue: "",
+		},
+		{
+			Input: []map[string]interface{}{getTestDriftLive(func(obj map[string]interface{}) {
+				containers := obj["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})["containers"].([]interface{})
+				containers[0].(map[string]interface{})["args"] = []interface{}{}
+			}, desired), getTestDriftLive(func(live map[string]interface{}) {
+				containers := live["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})["containers"].([]interface{})
+				containers[0].(map[string]interface{})["args"] = []interface{}{"--debug"}
+			}, desired)},
+			ReturnValue: "spec.template.spec.containers[0].args",
+		},
+	}
+}
+
+func getTestDriftSecret(field, password string) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata":   map[string]interface{}{"name": "foo"},
+		field:        map[string]interface{}{"password": password},
+	}
+}
+
+func getTestDriftResources(cpu, memory interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   map[string]interface{}{"name": "foo"},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{
+					"name": "foo",
+					"resources": map[string]interface{}{
+						"limits": map[string]interface{}{"cpu": cpu, "memory": memory},
+					},
+				},
+			},
+		},
+	}
+}
+
+func getTestDriftLive(modify func(map[string]interface{}), desired map[string]interface{}) map[string]interface{} {
+	live := (&unstructured.Unstructured{Object: desired}).DeepCopy().Object
+	modify(live)
+	return live
+}
@@ -0,0 +1,68 @@
+package helm
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// GetTestDeployment returns a deployment with three replicas of which the given number is available
+func GetTestDeployment(available int32) *appsv1.Deployment {
+	replicas := int32(3)
+
+	return &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "helm", Generation: 2},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 2,
+			Replicas:           3,
+			UpdatedReplicas:    3,
+			AvailableReplicas:  available,
+		},
+	}
+}
+
+// GetTestJob returns a job with the given condition set to true
+func GetTestJob(condition batchv1.JobConditionType) *batchv1.Job {
+	return &batchv1.Job{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "batch/v1", Kind: "Job"},
+		ObjectMeta: metav1.ObjectMeta{Name: "migrate", Namespace: "helm"},
+		Status: batchv1.JobStatus{
+			Conditions: []batchv1.JobCondition{
+				{Type: condition, Status: v1.ConditionTrue, Message: "backoff limit exceeded"},
+			},
+		},
+	}
+}
+
+// GetTestLoadBalancer returns a service of type load balancer without ingress
+func GetTestLoadBalancer() *v1.Service {
+	return &v1.Service{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "helm"},
+		Spec:       v1.ServiceSpec{Type: v1.ServiceTypeLoadBalancer},
+	}
+}
+
+// GetTestCustomResource returns a custom resource with a ready condition and a phase
+func GetTestCustomResource(ready, phase string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "example.com/v1",
+			"kind":       "Database",
+			"metadata": map[string]interface{}{
+				"name":      "db",
+				"namespace": "helm",
+			},
+			"status": map[string]interface{}{
+				"phase": phase,
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Ready", "status": ready, "message": "database " + phase},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,30 @@
+package helm
+
+import (
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// GetTestHelmReleases returns deployed helm releases of two charts with and without user supplied values
+func GetTestHelmReleases() []*release.Release {
+	return []*release.Release{
+		{
+			Name:      "web",
+			Namespace: "apps",
+			Chart:     GetTestRollbackChart("0.2.0"),
+			Config:    map[string]interface{}{"replicas": float64(2)},
+		},
+		{
+			Name:      "api",
+			Namespace: "apps",
+			Chart:     GetTestRollbackChart("0.1.0"),
+		},
+		{
+			Name:      "cache",
+			Namespace: "apps",
+			Chart: &chart.Chart{
+				Metadata: &chart.Metadata{APIVersion: "v2", Name: "redis", Version: "1.0.0"},
+			},
+		},
+	}
+}
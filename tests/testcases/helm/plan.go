@@ -0,0 +1,10 @@
+package helm
+
+// GetTestSecretManifests returns a deployed manifest with a secret and two desired ones with other values of the same keys
+func GetTestSecretManifests() (string, string, string) {
+	secret := func(password string) string {
+		return "apiVersion: v1\nkind: Secret\nmetadata:\n  name: credentials\n  namespace: default\ndata:\n  username: dXNlcg==\nstringData:\n  password: " + password + "\n"
+	}
+
+	return secret("c2VjcmV0"), secret("new-password"), secret("aW4gcmFuZG9t")
+}
@@ -0,0 +1,29 @@
+package helm
+
+import (
+	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
+)
+
+// GetTestPostRenderer returns a post renderer with a strategic merge patch, a JSON6902 patch and common metadata
+func GetTestPostRenderer() *helmv1alpha1.PostRenderer {
+	return &helmv1alpha1.PostRenderer{
+		Patches: []helmv1alpha1.PostRendererPatch{
+			{
+				Patch: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: rollback\ndata:\n  patched: strategic\n",
+			},
+			{
+				Patch: "- op: add\n  path: /data/json\n  value: patched\n",
+				Target: &helmv1alpha1.PatchTarget{
+					Kind: "ConfigMap",
+					Name: "rollback",
+				},
+			},
+		},
+		CommonLabels: map[string]string{
+			"team": "a",
+		},
+		CommonAnnotations: map[string]string{
+			"note": "b",
+		},
+	}
+}
@@ -0,0 +1,28 @@
+package helm
+
+import (
+	"errors"
+	"io"
+
+	"helm.sh/helm/v3/pkg/chart"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+)
+
+// GetTestHookChart returns a minimal chart with the given version and a test hook
+func GetTestHookChart(version string) *chart.Chart {
+	c := GetTestRollbackChart(version)
+	c.Templates = append(c.Templates, &chart.File{
+		Name: "templates/tests/connection.yaml",
+		Data: []byte("apiVersion: v1\nkind: Pod\nmetadata:\n  name: rollback-test\n  annotations:\n    helm.sh/hook: test\nspec:\n  containers:\n  - name: test\n    image: busybox\n  restartPolicy: Never\n"),
+	})
+
+	return c
+}
+
+// GetTestFailingHookKubeClient returns a fake kube client which fails on waiting for hooks
+func GetTestFailingHookKubeClient() *kubefake.FailingKubeClient {
+	return &kubefake.FailingKubeClient{
+		PrintingKubeClient:   kubefake.PrintingKubeClient{Out: io.Discard},
+		WatchUntilReadyError: errors.New("fake test failure"),
+	}
+}
@@ -0,0 +1,26 @@
+package helm
+
+import (
+	"errors"
+	"io"
+
+	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+)
+
+// GetTestFailingKubeClient returns a fake kube client which fails on waiting for resources
+func GetTestFailingKubeClient() *kubefake.FailingKubeClient {
+	return &kubefake.FailingKubeClient{
+		PrintingKubeClient: kubefake.PrintingKubeClient{Out: io.Discard},
+		WaitError:          errors.New("fake wait error"),
+	}
+}
+
+// GetTestRemediationPolicy returns a policy which remediates failed installs and upgrades after one retry
+func GetTestRemediationPolicy() *helmv1alpha1.RemediationPolicy {
+	return &helmv1alpha1.RemediationPolicy{
+		Retries:            1,
+		RollbackOnFailure:  true,
+		UninstallOnFailure: true,
+	}
+}
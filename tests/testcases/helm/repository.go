@@ -157,3 +157,118 @@ func GetTestRepoIndexFile(name string) *repo.IndexFile {
 		},
 	}
 }
+
+// GetTestRepoOCISpec returns a repository resource pointing to an oci registry for testing
+func GetTestRepoOCISpec(host string) *helmv1alpha1.Repository {
+	return &helmv1alpha1.Repository{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "oci",
+		},
+		Spec: helmv1alpha1.RepositorySpec{
+			Name:      "oci",
+			URL:       "oci://" + host + "/charts",
+			PlainHTTP: true,
+			Charts: []helmv1alpha1.Entry{
+				{
+					Name:     "busybox",
+					Versions: []string{"0.1.0"},
+				},
+			},
+		},
+	}
+}
+
+// GetTestRepoGitSpec returns a repository resource pointing to a git repository for testing
+func GetTestRepoGitSpec(url string) *helmv1alpha1.Repository {
+	return &helmv1alpha1.Repository{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "git",
+		},
+		Spec: helmv1alpha1.RepositorySpec{
+			Name: "git",
+			URL:  url,
+			Git: &helmv1alpha1.GitSource{
+				Branch: "main",
+				Path:   "charts",
+			},
+			Charts: []helmv1alpha1.Entry{
+				{
+					Name:     "busybox",
+					Versions: []string{"0.1.0"},
+				},
+			},
+		},
+	}
+}
+
+// GetTestRepoSyncSpec returns a repository resource with enabled sync for testing
+func GetTestRepoSyncSpec(url string) *helmv1alpha1.Repository {
+	return &helmv1alpha1.Repository{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "sync",
+			Generation: 1,
+		},
+		Spec: helmv1alpha1.RepositorySpec{
+			Name: "sync",
+			URL:  url,
+			Sync: helmv1alpha1.Sync{
+				Enabled:  true,
+				Interval: 60,
+			},
+			Charts: []helmv1alpha1.Entry{
+				{
+					Name: "busybox",
+				},
+			},
+		},
+	}
+}
+
+// GetTestRepoFilterSpec returns a repository resource with chart filters for testing
+func GetTestRepoFilterSpec(url string) *helmv1alpha1.Repository {
+	return &helmv1alpha1.Repository{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "filter",
+		},
+		Spec: helmv1alpha1.RepositorySpec{
+			Name: "filter",
+			URL:  url,
+			Filter: &helmv1alpha1.ChartFilter{
+				Include: []string{"testing*", "/^busy.*$/"},
+				Exclude: []string{"testing-nested"},
+				Versions: map[string]string{
+					"testing":     "<0.2.0",
+					"testing-dep": ">=1.0.0",
+				},
+			},
+		},
+	}
+}
+
+// GetTestRepoTLSSpec returns a repository resource with a tls secret for testing
+func GetTestRepoTLSSpec(url string) *helmv1alpha1.Repository {
+	return &helmv1alpha1.Repository{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "tls",
+		},
+		Spec: helmv1alpha1.RepositorySpec{
+			Name:      "tls",
+			URL:       url,
+			TLSSecret: "tls",
+		},
+	}
+}
+
+// GetTestRepoTokenSpec returns a repository resource with an auth secret for bearer token auth for testing
+func GetTestRepoTokenSpec(url string) *helmv1alpha1.Repository {
+	return &helmv1alpha1.Repository{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "token",
+		},
+		Spec: helmv1alpha1.RepositorySpec{
+			Name:       "token",
+			URL:        url,
+			AuthSecret: "token",
+		},
+	}
+}
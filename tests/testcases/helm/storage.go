@@ -0,0 +1,89 @@
+package helm
+
+import (
+	"math/rand"
+
+	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/repo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GetTestStorageChart returns a helm chart with templates in subdirectories and crds for testing storage backends
+func GetTestStorageChart() *chart.Chart {
+	return &chart.Chart{
+		Metadata: GetTestStorageIndex()[0].Metadata,
+		Templates: []*chart.File{
+			{Name: "templates/deployment.yaml", Data: []byte("kind: Deployment")},
+			{Name: "templates/sub/service.yaml", Data: []byte("kind: Service")},
+		},
+		Files: []*chart.File{
+			{Name: "crds/crd.yaml", Data: []byte("kind: CustomResourceDefinition")},
+		},
+	}
+}
+
+// GetTestStorageIndex returns the index entries of the storage test chart
+func GetTestStorageIndex() repo.ChartVersions {
+	return repo.ChartVersions{
+		{
+			Metadata: &chart.Metadata{APIVersion: "v2", Name: "storage", Version: "0.1.0"},
+			URLs:     []string{"storage-0.1.0.tgz"},
+		},
+	}
+}
+
+// GetTestStorageRepoSpec returns a repository resource with the given storage backend for testing
+func GetTestStorageRepoSpec(url string, storage *helmv1alpha1.ChartStorage) *helmv1alpha1.Repository {
+	return &helmv1alpha1.Repository{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "storage",
+		},
+		Spec: helmv1alpha1.RepositorySpec{
+			Name:    "storage",
+			URL:     url,
+			Storage: storage,
+		},
+	}
+}
+
+// GetTestStorageChartSpec returns a chart resource of the storage test chart
+func GetTestStorageChartSpec() *helmv1alpha1.Chart {
+	return &helmv1alpha1.Chart{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "storage",
+			Namespace: "default",
+			Labels: map[string]string{
+				"yaho.soer3n.dev/chart": "storage",
+				"yaho.soer3n.dev/repo":  "storage",
+			},
+		},
+		Spec: helmv1alpha1.ChartSpec{
+			Name:       "storage",
+			Repository: "storage",
+			Versions:   []string{"0.1.0"},
+		},
+	}
+}
+
+// GetTestOversizedChart returns the storage test chart with a template exceeding the configmap size limit
+func GetTestOversizedChart() *chart.Chart {
+	c := GetTestStorageChart()
+	data := make([]byte, 2*1024*1024)
+
+	// random data can not be compressed below the size limit
+	_, _ = rand.New(rand.NewSource(1)).Read(data)
+	c.Templates = append(c.Templates, &chart.File{Name: "templates/large.yaml", Data: data})
+
+	return c
+}
+
+// GetTestVerifyRepoSpec returns a repository resource with provenance verification for testing
+func GetTestVerifyRepoSpec(url string) *helmv1alpha1.Repository {
+	r := GetTestStorageRepoSpec(url, nil)
+	r.Spec.Verify = &helmv1alpha1.ChartVerification{
+		KeyringSecret: "keyring",
+	}
+
+	return r
+}
@@ -0,0 +1,25 @@
+package helm
+
+import (
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// GetTestNotesChart returns a minimal chart which renders the given notes
+func GetTestNotesChart(version, notes string) *chart.Chart {
+	c := GetTestRollbackChart(version)
+	c.Metadata.AppVersion = "1.0.0"
+	c.Templates = append(c.Templates, &chart.File{
+		Name: "templates/NOTES.txt",
+		Data: []byte(notes),
+	})
+
+	return c
+}
+
+// GetTestSummaryManifest returns a manifest with multiple documents for the summary of a release
+func GetTestSummaryManifest() string {
+	return "---\n# Source: summary/templates/service.yaml\napiVersion: v1\nkind: Service\nmetadata:\n  name: web\n  namespace: app\n" +
+		"---\n# Source: summary/templates/deployment.yaml\napiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n  namespace: app\n" +
+		"---\n# Source: summary/templates/empty.yaml\n" +
+		"---\n# Source: summary/templates/clusterrole.yaml\napiVersion: rbac.authorization.k8s.io/v1\nkind: ClusterRole\nmetadata:\n  name: web\n"
+}
@@ -0,0 +1,38 @@
+package helm
+
+import (
+	"errors"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+// failingDriver is a storage driver whose queries fail like an unreachable storage backend
+type failingDriver struct {
+	*driver.Memory
+}
+
+func (d *failingDriver) Query(_ map[string]string) ([]*release.Release, error) {
+	return nil, errors.New("storage unavailable")
+}
+
+// GetTestFailingStorageActionConfig returns an action config whose release storage can't be queried
+func GetTestFailingStorageActionConfig() *action.Configuration {
+	config := GetTestActionConfig()
+	config.Releases = storage.Init(&failingDriver{Memory: driver.NewMemory()})
+	return config
+}
+
+// GetTestCRDChart returns a minimal chart with a crd in its crds directory
+func GetTestCRDChart(version string) *chart.Chart {
+	c := GetTestRollbackChart(version)
+	c.Files = append(c.Files, &chart.File{
+		Name: "crds/crd.yaml",
+		Data: []byte("apiVersion: apiextensions.k8s.io/v1\nkind: CustomResourceDefinition\nmetadata:\n  name: tests.example.com\n"),
+	})
+
+	return c
+}
@@ -0,0 +1,127 @@
+package helm
+
+import (
+	"time"
+
+	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
+	inttypes "github.com/soer3n/yaho/tests/mocks/types"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// UpgradeInput represents input data for resolving the target version of a release
+type UpgradeInput struct {
+	Constraint string
+	Deployed   string
+	Policy     *helmv1alpha1.UpgradePolicy
+	Now        time.Time
+}
+
+// UpgradeResult represents the expected target version and delay of a pending upgrade
+type UpgradeResult struct {
+	Version string
+	Delay   time.Duration
+	Error   string
+}
+
+// GetTestUpgradeIndex returns chart versions for testing automatic upgrades
+func GetTestUpgradeIndex() repo.ChartVersions {
+	index := repo.ChartVersions{}
+
+	for _, v := range []string{"1.0.0", "1.0.1", "1.1.0", "2.0.0"} {
+		index = append(index, &repo.ChartVersion{Metadata: &chart.Metadata{Name: "testing", Version: v}})
+	}
+
+	return index
+}
+
+// GetTestUpgradeSpecs returns testcases for resolving the target version of a release
+func GetTestUpgradeSpecs() []inttypes.TestCase {
+	// monday
+	now := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	return []inttypes.TestCase{
+		{
+			Input:       UpgradeInput{Constraint: ">=1.0.0", Now: now},
+			ReturnValue: UpgradeResult{Version: "2.0.0"},
+		},
+		{
+			Input:       UpgradeInput{Constraint: ">=1.0.0", Deployed: "1.0.0", Now: now},
+			ReturnValue: UpgradeResult{Version: "1.0.0"},
+		},
+		{
+			Input:       UpgradeInput{Constraint: ">=1.0.0", Deployed: "1.0.0", Policy: &helmv1alpha1.UpgradePolicy{Type: "patch"}, Now: now},
+			ReturnValue: UpgradeResult{Version: "1.0.1"},
+		},
+		{
+			Input:       UpgradeInput{Constraint: ">=1.0.0", Deployed: "1.0.0", Policy: &helmv1alpha1.UpgradePolicy{Type: "minor"}, Now: now},
+			ReturnValue: UpgradeResult{Version: "1.1.0"},
+		},
+		{
+			Input:       UpgradeInput{Constraint: ">=1.0.0", Deployed: "1.0.0", Policy: &helmv1alpha1.UpgradePolicy{Type: "any"}, Now: now},
+			ReturnValue: UpgradeResult{Version: "2.0.0"},
+		},
+		{
+			Input:       UpgradeInput{Constraint: "~2.0.0", Deployed: "1.0.0", Now: now},
+			ReturnValue: UpgradeResult{Version: "2.0.0"},
+		},
+		{
+			Input: UpgradeInput{Constraint: ">=1.0.0", Deployed: "1.0.0", Now: now, Policy: &helmv1alpha1.UpgradePolicy{
+				Type:              "any",
+				MaintenanceWindow: &helmv1alpha1.MaintenanceWindow{Start: "22:00", End: "02:00", Days: []helmv1alpha1.Weekday{"Mon"}},
+			}},
+			ReturnValue: UpgradeResult{Version: "1.0.0", Delay: 10 * time.Hour},
+		},
+		{
+			Input: UpgradeInput{Constraint: ">=1.0.0", Deployed: "1.0.0", Now: now.Add(13 * time.Hour), Policy: &helmv1alpha1.UpgradePolicy{
+				Type:              "any",
+				MaintenanceWindow: &helmv1alpha1.MaintenanceWindow{Start: "22:00", End: "02:00", Days: []helmv1alpha1.Weekday{"Mon"}},
+			}},
+			ReturnValue: UpgradeResult{Version: "2.0.0"},
+		},
+		{
+			Input: UpgradeInput{Constraint: ">=1.0.0", Deployed: "1.0.0", Now: now, Policy: &helmv1alpha1.UpgradePolicy{
+				Type:              "any",
+				MaintenanceWindow: &helmv1alpha1.MaintenanceWindow{Start: "10:00", End: "14:00", Days: []helmv1alpha1.Weekday{"Tue"}},
+			}},
+			ReturnValue: UpgradeResult{Version: "1.0.0", Delay: 22 * time.Hour},
+		},
+		{
+			// the window of sunday is still open on monday morning
+			Input: UpgradeInput{Constraint: ">=1.0.0", Deployed: "1.0.0", Now: now.Add(-11 * time.Hour), Policy: &helmv1alpha1.UpgradePolicy{
+				Type:              "any",
+				MaintenanceWindow: &helmv1alpha1.MaintenanceWindow{Start: "23:00", End: "02:00", Days: []helmv1alpha1.Weekday{"Sun"}},
+			}},
+			ReturnValue: UpgradeResult{Version: "2.0.0"},
+		},
+		{
+			Input: UpgradeInput{Constraint: ">=1.0.0", Deployed: "1.0.0", Now: now, Policy: &helmv1alpha1.UpgradePolicy{
+				Type:              "any",
+				MaintenanceWindow: &helmv1alpha1.MaintenanceWindow{Start: "23:00", End: "02:00", Days: []helmv1alpha1.Weekday{"Sun"}},
+			}},
+			ReturnValue: UpgradeResult{Version: "1.0.0", Delay: 6*24*time.Hour + 11*time.Hour},
+		},
+		{
+			// invalid windows don't allow upgrades
+			Input: UpgradeInput{Constraint: ">=1.0.0", Deployed: "1.0.0", Now: now, Policy: &helmv1alpha1.UpgradePolicy{
+				Type:              "any",
+				MaintenanceWindow: &helmv1alpha1.MaintenanceWindow{Start: "22:00", End: "2h"},
+			}},
+			ReturnValue: UpgradeResult{Error: "invalid end \"2h\" of maintenance window"},
+		},
+		{
+			Input: UpgradeInput{Constraint: ">=1.0.0", Deployed: "1.0.0", Now: now, Policy: &helmv1alpha1.UpgradePolicy{
+				Type:              "any",
+				MaintenanceWindow: &helmv1alpha1.MaintenanceWindow{Start: "22:00", End: "02:00", Days: []helmv1alpha1.Weekday{"Monday"}},
+			}},
+			ReturnValue: UpgradeResult{Error: "invalid day \"Monday\" of maintenance window"},
+		},
+		{
+			Input: UpgradeInput{Constraint: ">=1.0.0", Deployed: "1.0.0", Now: now, Policy: &helmv1alpha1.UpgradePolicy{
+				Type:              "any",
+				MaintenanceWindow: &helmv1alpha1.MaintenanceWindow{Start: "22:00", End: "22:00"},
+			}},
+			ReturnValue: UpgradeResult{Error: "is empty"},
+		},
+	}
+}
@@ -1,16 +1,34 @@
 package helm
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/go-logr/logr/funcr"
 	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
 	"github.com/soer3n/yaho/internal/chartversion"
 	"github.com/soer3n/yaho/internal/utils"
 	helmmocks "github.com/soer3n/yaho/tests/mocks/helm"
 	testcases "github.com/soer3n/yaho/tests/testcases/helm"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/openpgp"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/provenance"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -36,3 +54,205 @@ func TestChartVersion(t *testing.T) {
 		assert.Equal(v.ReturnError["subCharts"], err)
 	}
 }
+
+func TestChartVersionStorage(t *testing.T) {
+	assert := assert.New(t)
+
+	_ = helmv1alpha1.AddToScheme(scheme.Scheme)
+
+	archive, err := chartutil.Save(testcases.GetTestStorageChart(), t.TempDir())
+	assert.Nil(err)
+	raw, err := os.ReadFile(archive)
+	assert.Nil(err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write(raw)
+	}))
+	defer server.Close()
+
+	backends := []*helmv1alpha1.ChartStorage{
+		nil,
+		{Type: "compressed"},
+		{Type: "secret"},
+		{Type: "filesystem", Path: t.TempDir()},
+	}
+
+	for _, backend := range backends {
+		r := testcases.GetTestStorageRepoSpec(server.URL, backend)
+		c := testcases.GetTestStorageChartSpec()
+		k8sClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(r, c).Build()
+
+		testObj, err := chartversion.New("0.1.0", "default", c, nil, testcases.GetTestStorageIndex(), scheme.Scheme, logf.Log, k8sClient, &http.Client{})
+		assert.Nil(err)
+		assert.Nil(testObj.Prepare(&action.Configuration{}))
+		assert.Nil(testObj.ManageSubResources())
+
+		// a new chart version loads its files from the storage backend
+		loaded, err := chartversion.New("0.1.0", "default", c, nil, testcases.GetTestStorageIndex(), scheme.Scheme, logf.Log, k8sClient, &http.Client{})
+		assert.Nil(err)
+
+		if assert.NotNil(loaded.Obj, "storage %v", backend) {
+			names := []string{}
+
+			for _, f := range loaded.Obj.Files {
+				names = append(names, f.Name)
+			}
+
+			assert.ElementsMatch([]string{"templates/deployment.yaml", "templates/sub/service.yaml", "crds/crd.yaml"}, names)
+			assert.Len(loaded.Obj.Templates, 2)
+		}
+	}
+}
+
+func TestChartVersionOversized(t *testing.T) {
+	assert := assert.New(t)
+
+	_ = helmv1alpha1.AddToScheme(scheme.Scheme)
+
+	archive, err := chartutil.Save(testcases.GetTestOversizedChart(), t.TempDir())
+	assert.Nil(err)
+	raw, err := os.ReadFile(archive)
+	assert.Nil(err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write(raw)
+	}))
+	defer server.Close()
+
+	r := testcases.GetTestStorageRepoSpec(server.URL, nil)
+	c := testcases.GetTestStorageChartSpec()
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(r, c).Build()
+
+	testObj, err := chartversion.New("0.1.0", "default", c, nil, testcases.GetTestStorageIndex(), scheme.Scheme, logf.Log, k8sClient, &http.Client{})
+	assert.Nil(err)
+	assert.Nil(testObj.Prepare(&action.Configuration{}))
+	assert.Nil(testObj.ManageSubResources())
+
+	// templates exceeding the size limit are stored in the format of the compressed storage
+	configmaps := &v1.ConfigMapList{}
+	assert.Nil(k8sClient.List(context.TODO(), configmaps, client.MatchingLabels{"yaho.soer3n.dev/type": "tmpl"}))
+
+	if assert.Len(configmaps.Items, 3) {
+		for _, configmap := range configmaps.Items {
+			assert.Contains(configmap.Labels, "yaho.soer3n.dev/chunk")
+			assert.LessOrEqual(len(configmap.BinaryData["chunk"]), 768*1024)
+		}
+	}
+
+	loaded, err := chartversion.New("0.1.0", "default", c, nil, testcases.GetTestStorageIndex(), scheme.Scheme, logf.Log, k8sClient, &http.Client{})
+	assert.Nil(err)
+
+	if assert.NotNil(loaded.Obj) {
+		assert.Len(loaded.Obj.Templates, 3)
+
+		for _, f := range loaded.Obj.Templates {
+			if f.Name == "templates/large.yaml" {
+				assert.Equal(testcases.GetTestOversizedChart().Templates[2].Data, f.Data)
+			}
+		}
+	}
+
+	// a missing trailing chunk is detected by the recorded count
+	for _, configmap := range configmaps.Items {
+		assert.Equal("3", configmap.Annotations["yaho.soer3n.dev/chunks"])
+
+		if configmap.Labels["yaho.soer3n.dev/chunk"] == "2" {
+			assert.Nil(k8sClient.Delete(context.TODO(), &configmap))
+		}
+	}
+
+	logs := []string{}
+	logger := funcr.New(func(prefix, args string) {
+		logs = append(logs, args)
+	}, funcr.Options{})
+
+	loaded, err = chartversion.New("0.1.0", "default", c, nil, testcases.GetTestStorageIndex(), scheme.Scheme, logger, k8sClient, &http.Client{})
+	assert.Nil(err)
+	assert.Contains(strings.Join(logs, "\n"), "chunk 3 of 3 is missing")
+}
+
+func TestChartVersionVerify(t *testing.T) {
+	assert := assert.New(t)
+
+	_ = helmv1alpha1.AddToScheme(scheme.Scheme)
+
+	dir := t.TempDir()
+	entity, err := openpgp.NewEntity("yaho", "", "yaho@example.com", nil)
+	assert.Nil(err)
+
+	secretKeyring, err := os.Create(filepath.Join(dir, "secring.gpg"))
+	assert.Nil(err)
+	assert.Nil(entity.SerializePrivate(secretKeyring, nil))
+	assert.Nil(secretKeyring.Close())
+
+	publicKeyring := &bytes.Buffer{}
+	assert.Nil(entity.Serialize(publicKeyring))
+
+	archive, err := chartutil.Save(testcases.GetTestStorageChart(), dir)
+	assert.Nil(err)
+	raw, err := os.ReadFile(archive)
+	assert.Nil(err)
+
+	signer, err := provenance.NewFromKeyring(filepath.Join(dir, "secring.gpg"), "yaho")
+	assert.Nil(err)
+	prov, err := signer.ClearSign(archive)
+	assert.Nil(err)
+
+	cases := []struct {
+		archive []byte
+		prov    string
+		digest  string
+		valid   bool
+	}{
+		{archive: raw, prov: prov, valid: true},
+		{archive: raw, prov: prov, digest: fmt.Sprintf("%x", sha256.Sum256(raw)), valid: true},
+		{archive: raw, prov: prov, digest: "sha256:1234"},
+		{archive: raw},
+		{archive: append(raw[:len(raw):len(raw)], 0), prov: prov},
+	}
+
+	for _, v := range cases {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if strings.HasSuffix(req.URL.Path, ".prov") {
+				if v.prov == "" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+
+				_, _ = w.Write([]byte(v.prov))
+				return
+			}
+
+			_, _ = w.Write(v.archive)
+		}))
+
+		r := testcases.GetTestVerifyRepoSpec(server.URL)
+		c := testcases.GetTestStorageChartSpec()
+		secret := &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "keyring",
+				Namespace: "default",
+			},
+			Data: map[string][]byte{
+				"keyring.gpg": publicKeyring.Bytes(),
+			},
+		}
+		k8sClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(r, c, secret).Build()
+
+		index := testcases.GetTestStorageIndex()
+		index[0].Digest = v.digest
+
+		testObj, err := chartversion.New("0.1.0", "default", c, nil, index, scheme.Scheme, logf.Log, k8sClient, &http.Client{})
+		assert.Nil(err)
+
+		err = testObj.Prepare(&action.Configuration{})
+
+		if v.valid {
+			assert.Nil(err)
+		} else {
+			assert.ErrorIs(err, chartversion.ErrVerificationFailed)
+		}
+
+		server.Close()
+	}
+}
@@ -1,15 +1,29 @@
 package helm
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
 	"github.com/soer3n/yaho/internal/release"
+	"github.com/soer3n/yaho/internal/values"
 	helmmocks "github.com/soer3n/yaho/tests/mocks/helm"
 	testcases "github.com/soer3n/yaho/tests/testcases/helm"
 	"github.com/stretchr/testify/assert"
+	"helm.sh/helm/v3/pkg/action"
+	helmchart "helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/cli"
+	helmrelease "helm.sh/helm/v3/pkg/release"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/kubectl/pkg/scheme"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
@@ -40,3 +54,747 @@ func TestReleaseUpdate(t *testing.T) {
 		assert.Equal(apiObj.ReturnError["remove"], err)
 	}
 }
+
+func TestReleaseTargetVersion(t *testing.T) {
+	assert := assert.New(t)
+	index := testcases.GetTestUpgradeIndex()
+
+	for _, apiObj := range testcases.GetTestUpgradeSpecs() {
+		input := apiObj.Input.(testcases.UpgradeInput)
+		expected := apiObj.ReturnValue.(testcases.UpgradeResult)
+
+		version, delay, err := release.GetTargetVersion(input.Constraint, input.Deployed, index, input.Policy, input.Now)
+
+		if expected.Error != "" {
+			assert.ErrorContains(err, expected.Error)
+			continue
+		}
+
+		assert.Nil(err)
+		assert.Equal(expected.Version, version)
+		assert.Equal(expected.Delay, delay)
+	}
+}
+
+func TestReleaseDriftReason(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, v := range testcases.GetTestDriftSpecs() {
+		objs := v.Input.([]map[string]interface{})
+		reason, err := release.GetDriftReason(&unstructured.Unstructured{Object: objs[0]}, &unstructured.Unstructured{Object: objs[1]})
+		assert.Nil(err)
+		assert.Equal(v.ReturnValue, reason)
+	}
+}
+
+func TestReleaseHeal(t *testing.T) {
+	assert := assert.New(t)
+
+	testObj := &release.Release{
+		Name:           "heal",
+		Chart:          testcases.GetTestRollbackChart("0.1.0"),
+		Config:         testcases.GetTestActionConfig(),
+		ValuesTemplate: &values.ValueTemplate{Values: map[string]interface{}{"foo": "bar"}},
+	}
+
+	assert.Nil(testObj.Update())
+
+	// a newer chart and changed values which are held back are not applied by healing
+	testObj.Chart = testcases.GetTestRollbackChart("0.2.0")
+	testObj.ValuesTemplate = &values.ValueTemplate{Values: map[string]interface{}{"foo": "baz"}}
+	assert.Nil(testObj.Heal())
+	assert.Equal(2, testObj.Revision)
+
+	rel, err := action.NewGet(testObj.Config).Run("heal")
+	assert.Nil(err)
+	assert.Equal("0.1.0", rel.Chart.Metadata.Version)
+	assert.Equal(map[string]interface{}{"foo": "bar"}, rel.Config)
+}
+
+func TestReleaseRollback(t *testing.T) {
+	assert := assert.New(t)
+
+	testObj := &release.Release{
+		Name:           "rollback",
+		Chart:          testcases.GetTestRollbackChart("0.1.0"),
+		Config:         testcases.GetTestActionConfig(),
+		ValuesTemplate: &values.ValueTemplate{Values: map[string]interface{}{}},
+	}
+
+	assert.Nil(testObj.Update())
+	assert.Equal(1, testObj.Revision)
+
+	testObj.Chart = testcases.GetTestRollbackChart("0.2.0")
+	assert.Nil(testObj.Update())
+	assert.Equal(2, testObj.Revision)
+
+	revision := 1
+	testObj.RollbackTo = &revision
+	testObj.Generation = 2
+	assert.Nil(testObj.Update())
+	assert.Equal(3, testObj.Revision)
+	assert.Equal(&helmv1alpha1.ReleaseRollback{Revision: 1, Generation: 2, DeployedRevision: 3}, testObj.Rollback)
+
+	if assert.Len(testObj.History, 3) {
+		assert.Equal("0.1.0", testObj.History[0].Version)
+		assert.Equal("deployed", testObj.History[0].Status)
+		assert.Equal("Rollback to 1", testObj.History[0].Description)
+		assert.Equal("superseded", testObj.History[1].Status)
+	}
+
+	// a release which is already rolled back is not rolled back again
+	assert.Nil(testObj.Update())
+	assert.Equal(3, testObj.Revision)
+
+	// the rollback is applied again if another revision was deployed since
+	testObj.Rollback.DeployedRevision = 2
+	assert.Nil(testObj.Update())
+	assert.Equal(4, testObj.Revision)
+	assert.Equal(4, testObj.Rollback.DeployedRevision)
+
+	// and for a new generation of the spec
+	testObj.Generation = 3
+	assert.Nil(testObj.Update())
+	assert.Equal(5, testObj.Revision)
+	assert.Equal(&helmv1alpha1.ReleaseRollback{Revision: 1, Generation: 3, DeployedRevision: 5}, testObj.Rollback)
+
+	// the release returns to the chart version of the spec
+	testObj.RollbackTo = nil
+	assert.Nil(testObj.Update())
+	assert.Equal(6, testObj.Revision)
+	assert.Equal("0.2.0", testObj.History[0].Version)
+}
+
+func TestReleaseRemediation(t *testing.T) {
+	assert := assert.New(t)
+
+	testObj := &release.Release{
+		Name:           "remediation",
+		Chart:          testcases.GetTestRollbackChart("0.1.0"),
+		Config:         testcases.GetTestActionConfig(),
+		ValuesTemplate: &values.ValueTemplate{Values: map[string]interface{}{}},
+		Remediation:    testcases.GetTestRemediationPolicy(),
+		Flags:          &helmv1alpha1.Flags{Wait: true},
+	}
+
+	// a failed install is uninstalled
+	kubeClient := testObj.Config.KubeClient
+	testObj.Config.KubeClient = testcases.GetTestFailingKubeClient()
+	assert.NotNil(testObj.Update())
+
+	testObj.Config.KubeClient = kubeClient
+	action, err := testObj.Remediate()
+	assert.Nil(err)
+	assert.Equal("uninstalled", action)
+
+	// a failed upgrade is rolled back to the last deployed revision
+	assert.Nil(testObj.Update())
+	assert.Equal(1, testObj.Revision)
+
+	testObj.Chart = testcases.GetTestRollbackChart("0.2.0")
+	testObj.Config.KubeClient = testcases.GetTestFailingKubeClient()
+	assert.NotNil(testObj.Update())
+
+	testObj.Config.KubeClient = kubeClient
+	action, err = testObj.Remediate()
+	assert.Nil(err)
+	assert.Equal("rolledBack", action)
+	assert.Equal(3, testObj.Revision)
+
+	if assert.NotEmpty(testObj.History) {
+		assert.Equal("0.1.0", testObj.History[0].Version)
+		assert.Equal("Rollback to 1", testObj.History[0].Description)
+	}
+
+	// a deployed release is not remediated
+	action, err = testObj.Remediate()
+	assert.Nil(err)
+	assert.Equal("", action)
+}
+
+func TestReleaseRemediationDelay(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := []struct {
+		policy   *helmv1alpha1.RemediationPolicy
+		failures int
+		expected time.Duration
+	}{
+		{policy: &helmv1alpha1.RemediationPolicy{}, failures: 1, expected: 10 * time.Second},
+		{policy: &helmv1alpha1.RemediationPolicy{Backoff: 5}, failures: 1, expected: 5 * time.Second},
+		{policy: &helmv1alpha1.RemediationPolicy{Backoff: 5}, failures: 3, expected: 20 * time.Second},
+		{policy: &helmv1alpha1.RemediationPolicy{Backoff: 60}, failures: 10, expected: 10 * time.Minute},
+	}
+
+	for _, c := range cases {
+		assert.Equal(c.expected, release.GetRemediationDelay(c.policy, c.failures))
+	}
+}
+
+func TestReleaseRetryWait(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	instance := &helmv1alpha1.Release{}
+	instance.Generation = 2
+	assert.Equal(time.Duration(0), release.GetRetryWait(instance, now))
+
+	instance.Status.FailedGeneration = 2
+	instance.Status.NextRetryAt = &metav1.Time{Time: now.Add(20 * time.Second)}
+	assert.Equal(20*time.Second, release.GetRetryWait(instance, now))
+	assert.Equal(time.Duration(0), release.GetRetryWait(instance, now.Add(20*time.Second)))
+
+	// a spec change is applied without waiting
+	instance.Generation = 3
+	assert.Equal(time.Duration(0), release.GetRetryWait(instance, now))
+}
+
+func TestReleasePlan(t *testing.T) {
+	assert := assert.New(t)
+
+	testObj := &release.Release{
+		Name:           "plan",
+		Chart:          testcases.GetTestRollbackChart("0.1.0"),
+		Config:         testcases.GetTestActionConfig(),
+		ValuesTemplate: &values.ValueTemplate{Values: map[string]interface{}{}},
+	}
+
+	// all objects are added on install
+	plan, err := testObj.Plan()
+	assert.Nil(err)
+	assert.Equal([]string{"ConfigMap/rollback"}, plan.Added)
+	assert.NotEmpty(plan.Digest)
+
+	assert.Nil(testObj.Update())
+
+	// nothing is planned if the deployed revision is up to date
+	plan, err = testObj.Plan()
+	assert.Nil(err)
+	assert.Empty(plan.Digest)
+	assert.Empty(plan.Diff)
+
+	inputs, err := testObj.PlanInputs()
+	assert.Nil(err)
+	assert.Equal(plan.Inputs, inputs)
+
+	testObj.Chart = testcases.GetTestRollbackChart("0.2.0")
+
+	changedInputs, err := testObj.PlanInputs()
+	assert.Nil(err)
+	assert.NotEqual(inputs, changedInputs)
+
+	plan, err = testObj.Plan()
+	assert.Nil(err)
+	assert.Equal([]string{"ConfigMap/rollback"}, plan.Changed)
+	assert.Contains(plan.Diff, "-  version: 0.1.0")
+	assert.Contains(plan.Diff, "+  version: 0.2.0")
+
+	// planning does not change the deployed revision
+	assert.Equal(1, testObj.Revision)
+
+	plan, err = release.GetManifestDiff("kind: Secret\nmetadata:\n  name: old\n  namespace: default\n", "")
+	assert.Nil(err)
+	assert.Equal([]string{"Secret/default/old"}, plan.Removed)
+
+	// values of secrets are neither part of the diff nor of the digest
+	deployed, desired, random := testcases.GetTestSecretManifests()
+	plan, err = release.GetManifestDiff(deployed, desired)
+	assert.Nil(err)
+	assert.Equal([]string{"Secret/default/credentials"}, plan.Changed)
+	assert.NotContains(plan.Diff, "c2VjcmV0")
+	assert.NotContains(plan.Diff, "new-password")
+	assert.Contains(plan.Diff, "+  password: <redacted:changed>")
+
+	randomPlan, err := release.GetManifestDiff(deployed, random)
+	assert.Nil(err)
+	assert.Equal(plan.Digest, randomPlan.Digest)
+
+	plan, err = release.GetManifestDiff(deployed, deployed)
+	assert.Nil(err)
+	assert.Empty(plan.Digest)
+}
+
+func TestReleasePostRenderer(t *testing.T) {
+	assert := assert.New(t)
+
+	testObj := &release.Release{
+		Name:           "postrender",
+		Chart:          testcases.GetTestRollbackChart("0.1.0"),
+		Config:         testcases.GetTestActionConfig(),
+		ValuesTemplate: &values.ValueTemplate{Values: map[string]interface{}{}},
+		PostRenderer:   testcases.GetTestPostRenderer(),
+	}
+
+	assert.Nil(testObj.Update())
+
+	rel, err := action.NewGet(testObj.Config).Run("postrender")
+	assert.Nil(err)
+	assert.Contains(rel.Manifest, "patched: strategic")
+	assert.Contains(rel.Manifest, "json: patched")
+	assert.Contains(rel.Manifest, "team: a")
+	assert.Contains(rel.Manifest, "note: b")
+
+	// patches are applied on upgrades as well
+	testObj.Chart = testcases.GetTestRollbackChart("0.2.0")
+	assert.Nil(testObj.Update())
+
+	rel, err = action.NewGet(testObj.Config).Run("postrender")
+	assert.Nil(err)
+	assert.Equal(2, rel.Version)
+	assert.Contains(rel.Manifest, "version: 0.2.0")
+	assert.Contains(rel.Manifest, "patched: strategic")
+
+	// nothing is upgraded as long as the post renderer is unchanged
+	assert.Nil(testObj.Update())
+	assert.Equal(2, testObj.Revision)
+
+	// changed metadata of the post renderer is applied without a chart or values change
+	testObj.PostRenderer.CommonLabels["team"] = "c"
+	assert.Nil(testObj.Update())
+
+	rel, err = action.NewGet(testObj.Config).Run("postrender")
+	assert.Nil(err)
+	assert.Equal(3, rel.Version)
+	assert.Contains(rel.Manifest, "team: c")
+
+	// a removed post renderer is no longer applied
+	testObj.PostRenderer = nil
+	assert.Nil(testObj.Update())
+
+	rel, err = action.NewGet(testObj.Config).Run("postrender")
+	assert.Nil(err)
+	assert.Equal(4, rel.Version)
+	assert.NotContains(rel.Manifest, "patched: strategic")
+	assert.Empty(rel.Labels)
+
+	assert.Nil(testObj.Update())
+	assert.Equal(4, testObj.Revision)
+
+	// nothing is rendered without patches or metadata
+	assert.Nil(release.NewPostRenderer(&helmv1alpha1.PostRenderer{}))
+}
+
+func TestReleaseDependencies(t *testing.T) {
+	assert := assert.New(t)
+
+	database := testcases.GetTestDependentRelease("database", true)
+	app := testcases.GetTestDependentRelease("app", false, helmv1alpha1.ReleaseDependency{Name: "database"})
+	remote := testcases.GetTestDependentRelease("remote", false, helmv1alpha1.ReleaseDependency{Name: "app", Namespace: "helm"}, helmv1alpha1.ReleaseDependency{Name: "missing"})
+	lookup := testcases.GetTestReleaseLookup(database, app, remote)
+
+	message, err := release.GetUnreadyDependency(&app, lookup)
+	assert.Nil(err)
+	assert.Empty(message)
+
+	// the dependency has to be synced for its current generation
+	database.Generation = 2
+	message, err = release.GetUnreadyDependency(&app, testcases.GetTestReleaseLookup(database))
+	assert.Nil(err)
+	assert.Equal("dependency helm/database is not synced", message)
+
+	message, err = release.GetUnreadyDependency(&remote, lookup)
+	assert.Nil(err)
+	assert.Equal("dependency helm/app is not synced", message)
+
+	app.Status.Conditions[0].Status = metav1.ConditionTrue
+	message, err = release.GetUnreadyDependency(&remote, testcases.GetTestReleaseLookup(app))
+	assert.Nil(err)
+	assert.Equal("dependency helm/missing not found", message)
+
+	cycle, err := release.GetDependencyCycle(&remote, lookup)
+	assert.Nil(err)
+	assert.Nil(cycle)
+
+	database.Spec.DependsOn = []helmv1alpha1.ReleaseDependency{{Name: "remote"}}
+	cycle, err = release.GetDependencyCycle(&database, testcases.GetTestReleaseLookup(database, app, remote))
+	assert.Nil(err)
+	assert.Equal([]string{"helm/database", "helm/remote", "helm/app", "helm/database"}, cycle)
+
+	// dependents are uninstalled before their dependencies
+	assert.Equal([]string{"helm/app"}, release.GetDependents(&database, []helmv1alpha1.Release{database, app, remote}))
+	assert.Equal([]string{"helm/database"}, release.GetDependents(&remote, []helmv1alpha1.Release{database, app, remote}))
+	assert.Empty(release.GetDependents(&remote, []helmv1alpha1.Release{app, remote}))
+}
+
+func TestReleaseHealth(t *testing.T) {
+	assert := assert.New(t)
+
+	assessed, healthy, message, err := release.GetResourceHealth(testcases.GetTestDeployment(1), nil)
+	assert.Nil(err)
+	assert.True(assessed)
+	assert.False(healthy)
+	assert.Equal("1 of 3 replicas available", message)
+
+	_, healthy, _, err = release.GetResourceHealth(testcases.GetTestDeployment(3), nil)
+	assert.Nil(err)
+	assert.True(healthy)
+
+	// a rollout is unhealthy until the controller observed the new generation
+	deployment := testcases.GetTestDeployment(3)
+	deployment.Generation = 3
+	_, healthy, message, err = release.GetResourceHealth(deployment, nil)
+	assert.Nil(err)
+	assert.False(healthy)
+	assert.Equal("rollout not observed yet", message)
+
+	_, healthy, _, err = release.GetResourceHealth(testcases.GetTestJob(batchv1.JobComplete), nil)
+	assert.Nil(err)
+	assert.True(healthy)
+
+	_, healthy, message, err = release.GetResourceHealth(testcases.GetTestJob(batchv1.JobFailed), nil)
+	assert.Nil(err)
+	assert.False(healthy)
+	assert.Equal("failed: backoff limit exceeded", message)
+
+	_, healthy, message, err = release.GetResourceHealth(testcases.GetTestLoadBalancer(), nil)
+	assert.Nil(err)
+	assert.False(healthy)
+	assert.Equal("load balancer not provisioned", message)
+
+	// custom resources are only assessed with a rule
+	assessed, _, _, err = release.GetResourceHealth(testcases.GetTestCustomResource("True", "Running"), nil)
+	assert.Nil(err)
+	assert.False(assessed)
+
+	conditionRule := []helmv1alpha1.HealthRule{{Group: "example.com", Kind: "Database"}}
+	_, healthy, _, err = release.GetResourceHealth(testcases.GetTestCustomResource("True", "Running"), conditionRule)
+	assert.Nil(err)
+	assert.True(healthy)
+
+	_, healthy, message, err = release.GetResourceHealth(testcases.GetTestCustomResource("False", "Provisioning"), conditionRule)
+	assert.Nil(err)
+	assert.False(healthy)
+	assert.Equal("database Provisioning", message)
+
+	fieldRule := []helmv1alpha1.HealthRule{{Group: "example.com", Kind: "Database", Field: "status.phase", Values: []string{"Running"}}}
+	_, healthy, message, err = release.GetResourceHealth(testcases.GetTestCustomResource("False", "Provisioning"), fieldRule)
+	assert.Nil(err)
+	assert.False(healthy)
+	assert.Equal("status.phase is Provisioning", message)
+
+	// unhealthy dependencies are not ready
+	dependency := testcases.GetTestDependentRelease("database", true)
+	dependency.Status.Conditions = append(dependency.Status.Conditions, metav1.Condition{Type: "healthy", Status: metav1.ConditionFalse})
+	ready, reason := release.IsReleaseReady(&dependency)
+	assert.False(ready)
+	assert.Equal("is not healthy", reason)
+}
+
+func TestReleaseImport(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := release.GetImportedResources(testcases.GetTestHelmReleases(), release.ImportOptions{Namespace: "helm"})
+	assert.NotNil(err)
+
+	objects, err := release.GetImportedResources(testcases.GetTestHelmReleases(), release.ImportOptions{
+		Namespace:  "helm",
+		Config:     "apps-config",
+		Repository: "stable",
+		URL:        "https://charts.example.com",
+	})
+	assert.Nil(err)
+	assert.Len(objects, 7)
+
+	repository, ok := objects[0].(*helmv1alpha1.Repository)
+	assert.True(ok)
+	assert.Equal("https://charts.example.com", repository.Spec.URL)
+	assert.Equal([]helmv1alpha1.Entry{
+		{Name: "redis", Versions: []string{"1.0.0"}},
+		{Name: "rollback", Versions: []string{"0.1.0", "0.2.0"}},
+	}, repository.Spec.Charts)
+
+	// charts are named like the ones managed by the repository
+	chart, ok := objects[2].(*helmv1alpha1.Chart)
+	assert.True(ok)
+	assert.Equal("rollback-stable", chart.Name)
+	assert.Equal("stable", chart.Labels["yaho.soer3n.dev/repo"])
+
+	api, ok := objects[3].(*helmv1alpha1.Release)
+	assert.True(ok)
+	assert.Equal("api", api.Spec.Name)
+	assert.Equal("apps", *api.Spec.Namespace)
+	assert.Equal("0.1.0", api.Spec.Version)
+	assert.Equal("apps-config", *api.Spec.Config)
+	assert.Empty(api.Spec.Values)
+
+	values, ok := objects[5].(*helmv1alpha1.Values)
+	assert.True(ok)
+	assert.Equal("web-values", values.Name)
+	assert.JSONEq(`{"replicas":2}`, string(values.Spec.ValuesMap.Raw))
+
+	web, ok := objects[6].(*helmv1alpha1.Release)
+	assert.True(ok)
+	assert.Equal("helm", web.Namespace)
+	assert.Equal([]string{"web-values"}, web.Spec.Values)
+
+	// versions are ordered by semver instead of lexically
+	releases := []*helmrelease.Release{}
+
+	for i, v := range []string{"1.10.0", "1.9.0", "1.9.0-rc.1"} {
+		releases = append(releases, &helmrelease.Release{
+			Name:      fmt.Sprintf("cache-%v", i),
+			Namespace: "apps",
+			Chart: &helmchart.Chart{
+				Metadata: &helmchart.Metadata{APIVersion: "v2", Name: "redis", Version: v},
+			},
+		})
+	}
+
+	objects, err = release.GetImportedResources(releases, release.ImportOptions{
+		Namespace:  "helm",
+		Repository: "stable",
+		URL:        "https://charts.example.com",
+	})
+	assert.Nil(err)
+
+	repository, ok = objects[0].(*helmv1alpha1.Repository)
+	assert.True(ok)
+	assert.Equal([]helmv1alpha1.Entry{
+		{Name: "redis", Versions: []string{"1.9.0-rc.1", "1.9.0", "1.10.0"}},
+	}, repository.Spec.Charts)
+}
+
+func TestReleaseUninstall(t *testing.T) {
+	assert := assert.New(t)
+
+	newRelease := func(policy *helmv1alpha1.UninstallPolicy) *release.Release {
+		return &release.Release{
+			Name:           "uninstall",
+			Chart:          testcases.GetTestCRDChart("0.1.0"),
+			Config:         testcases.GetTestActionConfig(),
+			ValuesTemplate: &values.ValueTemplate{Values: map[string]interface{}{}},
+			Uninstall:      policy,
+		}
+	}
+
+	// the helm release is left untouched if orphaned
+	testObj := newRelease(&helmv1alpha1.UninstallPolicy{Policy: release.UninstallOrphan})
+	assert.Nil(testObj.Update())
+	assert.Nil(testObj.RemoveRelease())
+
+	rel, err := action.NewGet(testObj.Config).Run("uninstall")
+	assert.Nil(err)
+	assert.Equal(helmrelease.StatusDeployed, rel.Info.Status)
+
+	testObj = newRelease(&helmv1alpha1.UninstallPolicy{Policy: release.UninstallKeepHistory, DeleteCRDs: true})
+	assert.Nil(testObj.Update())
+	assert.Nil(testObj.RemoveRelease())
+
+	rel, err = action.NewGet(testObj.Config).Run("uninstall")
+	assert.Nil(err)
+	assert.Equal(helmrelease.StatusUninstalled, rel.Info.Status)
+
+	// a release with kept history is not uninstalled again
+	assert.Nil(testObj.RemoveRelease())
+
+	testObj = newRelease(&helmv1alpha1.UninstallPolicy{Wait: true, Timeout: 30, DeleteCRDs: true})
+	assert.Nil(testObj.Update())
+	assert.Nil(testObj.RemoveRelease())
+
+	_, err = action.NewGet(testObj.Config).Run("uninstall")
+	assert.NotNil(err)
+
+	// a release which is already gone is not an error
+	assert.Nil(testObj.RemoveRelease())
+
+	// errors on reading the release are returned instead of orphaning it
+	testObj = newRelease(nil)
+	testObj.Config = testcases.GetTestFailingStorageActionConfig()
+	assert.ErrorContains(testObj.RemoveRelease(), "storage unavailable")
+
+	assert.Equal(600*time.Second, release.GetUninstallGiveUpAfter(nil))
+	assert.Equal(30*time.Second, release.GetUninstallGiveUpAfter(&helmv1alpha1.UninstallPolicy{GiveUpAfter: 30}))
+}
+
+func TestReleaseSummary(t *testing.T) {
+	assert := assert.New(t)
+
+	testObj := &release.Release{
+		Name:           "summary",
+		Chart:          testcases.GetTestNotesChart("0.1.0", "release {{ .Release.Name }} deployed"),
+		Config:         testcases.GetTestActionConfig(),
+		ValuesTemplate: &values.ValueTemplate{Values: map[string]interface{}{}},
+	}
+
+	assert.Nil(testObj.Update())
+
+	summary, err := testObj.GetSummary()
+	assert.Nil(err)
+	assert.Nil(summary.Data)
+	assert.Equal(1, summary.Revision)
+	assert.Equal("rollback", summary.Chart)
+	assert.Equal("0.1.0", summary.Version)
+	assert.Equal("1.0.0", summary.AppVersion)
+	assert.Equal("release summary deployed", summary.Notes)
+	assert.False(summary.Deployed.IsZero())
+	assert.Equal(1, summary.ResourceCount)
+	assert.Equal([]helmv1alpha1.DeployedResource{{APIVersion: "v1", Kind: "ConfigMap", Name: "rollback"}}, summary.Resources)
+
+	// large notes are moved into the data of a configmap
+	testObj.Chart = testcases.GetTestNotesChart("0.2.0", strings.Repeat("x", 5*1024))
+	assert.Nil(testObj.Update())
+
+	summary, err = testObj.GetSummary()
+	assert.Nil(err)
+	assert.Equal(2, summary.Revision)
+	assert.Empty(summary.Notes)
+	assert.Nil(summary.Resources)
+	assert.Equal(1, summary.ResourceCount)
+	assert.Len(summary.Data["NOTES.txt"], 5*1024)
+	assert.Contains(summary.Data["resources.yaml"], "kind: ConfigMap")
+
+	resources, err := release.GetManifestResources(testcases.GetTestSummaryManifest())
+	assert.Nil(err)
+	assert.Equal([]helmv1alpha1.DeployedResource{
+		{APIVersion: "v1", Kind: "Service", Namespace: "app", Name: "web"},
+		{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "app", Name: "web"},
+		{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole", Name: "web"},
+	}, resources)
+}
+
+func TestReleaseNamespace(t *testing.T) {
+	assert := assert.New(t)
+	clientset := k8sfake.NewSimpleClientset(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "existing"}})
+
+	owned, err := release.EnsureNamespace(clientset, "created", "helm/test", map[string]string{"team": "a"}, map[string]string{"note": "b"})
+	assert.Nil(err)
+	assert.True(owned)
+
+	ns, err := clientset.CoreV1().Namespaces().Get(context.Background(), "created", metav1.GetOptions{})
+	assert.Nil(err)
+	assert.Equal("a", ns.Labels["team"])
+	assert.Equal("b", ns.Annotations["note"])
+
+	// an existing namespace is only owned by the release which created it
+	owned, err = release.EnsureNamespace(clientset, "created", "helm/other", nil, nil)
+	assert.Nil(err)
+	assert.False(owned)
+
+	owned, err = release.EnsureNamespace(clientset, "existing", "helm/test", nil, nil)
+	assert.Nil(err)
+	assert.False(owned)
+
+	deleted, err := release.DeleteNamespace(clientset, "existing", "helm/test")
+	assert.Nil(err)
+	assert.False(deleted)
+
+	deleted, err = release.DeleteNamespace(clientset, "created", "helm/other")
+	assert.Nil(err)
+	assert.False(deleted)
+
+	deleted, err = release.DeleteNamespace(clientset, "created", "helm/test")
+	assert.Nil(err)
+	assert.True(deleted)
+}
+
+func TestReleaseTests(t *testing.T) {
+	assert := assert.New(t)
+
+	testObj := &release.Release{
+		Name:           "tests",
+		Chart:          testcases.GetTestHookChart("0.1.0"),
+		Config:         testcases.GetTestActionConfig(),
+		ValuesTemplate: &values.ValueTemplate{Values: map[string]interface{}{}},
+	}
+
+	assert.Nil(testObj.Update())
+
+	result, err := testObj.RunTests(time.Minute)
+	assert.Nil(err)
+	assert.Equal("succeeded", result.Phase)
+	assert.Equal(1, result.Revision)
+
+	if assert.Len(result.Results, 1) {
+		assert.Equal("rollback-test", result.Results[0].Name)
+		assert.Equal("succeeded", result.Results[0].Phase)
+	}
+
+	testObj.Chart = testcases.GetTestHookChart("0.2.0")
+	assert.Nil(testObj.Update())
+
+	// failed tests are part of the result
+	kubeClient := testObj.Config.KubeClient
+	testObj.Config.KubeClient = testcases.GetTestFailingHookKubeClient()
+
+	result, err = testObj.RunTests(time.Minute)
+	assert.Nil(err)
+	assert.Equal("failed", result.Phase)
+	assert.Equal(2, result.Revision)
+
+	testObj.Config.KubeClient = kubeClient
+
+	rolledBack, err := testObj.RollbackFailedTests()
+	assert.Nil(err)
+	assert.True(rolledBack)
+	assert.Equal(3, testObj.Revision)
+	assert.Equal("0.1.0", testObj.History[0].Version)
+}
+
+func TestReleaseTestRuns(t *testing.T) {
+	assert := assert.New(t)
+
+	testObj := &release.Release{
+		Name:           "testruns",
+		Chart:          testcases.GetTestHookChart("0.1.0"),
+		Config:         testcases.GetTestActionConfig(),
+		ValuesTemplate: &values.ValueTemplate{Values: map[string]interface{}{}},
+	}
+
+	assert.Nil(testObj.Update())
+
+	// the tests are started in the background and the result is returned once they finished
+	runs := &release.TestRuns{}
+	result, err := runs.Run("helm/testruns", testObj, time.Minute, "1")
+	assert.Nil(err)
+	assert.Nil(result)
+
+	assert.Eventually(func() bool {
+		result, err = runs.Run("helm/testruns", testObj, time.Minute, "1")
+		return result != nil || err != nil
+	}, 10*time.Second, 10*time.Millisecond)
+
+	assert.Nil(err)
+
+	if assert.NotNil(result) {
+		assert.Equal("succeeded", result.Phase)
+		assert.Equal("1", result.Trigger)
+	}
+
+	// a finished run is returned only once
+	result, err = runs.Run("helm/testruns", testObj, time.Minute, "2")
+	assert.Nil(err)
+	assert.Nil(result)
+	runs.Forget("helm/testruns")
+
+	// the logs of each test pod and of all test pods are limited
+	logs := map[string][]byte{}
+
+	for i := 0; i < 12; i++ {
+		logs[fmt.Sprintf("test-%02d", i)] = []byte(strings.Repeat("x", 100*1024) + "failure")
+	}
+
+	limited := release.GetLimitedTestLogs(logs)
+	total := 0
+
+	for _, podLogs := range limited {
+		assert.LessOrEqual(len(podLogs), 64*1024)
+		total += len(podLogs)
+	}
+
+	assert.Len(limited, 12)
+	assert.LessOrEqual(total, 512*1024)
+	assert.True(strings.HasSuffix(limited["test-00"], "failure"))
+	assert.Empty(limited["test-11"])
+
+	// binary output and multibyte characters cut at the limit result in valid UTF-8
+	limited = release.GetLimitedTestLogs(map[string][]byte{
+		"test-binary":    {'o', 'k', 0xff, 0xfe, '\n'},
+		"test-multibyte": []byte(strings.Repeat("€", 21846)),
+	})
+
+	assert.True(utf8.ValidString(limited["test-binary"]))
+	assert.True(strings.HasPrefix(limited["test-binary"], "ok"))
+	assert.True(utf8.ValidString(limited["test-multibyte"]))
+	assert.LessOrEqual(len(limited["test-multibyte"]), 64*1024)
+	assert.Equal(strings.Repeat("€", 21845), limited["test-multibyte"])
+}
@@ -2,16 +2,34 @@ package helm
 
 import (
 	"context"
+	b64 "encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
 	"github.com/soer3n/yaho/internal/repository"
+	"github.com/soer3n/yaho/internal/utils"
+	"github.com/soer3n/yaho/tests/mocks"
 	helmmocks "github.com/soer3n/yaho/tests/mocks/helm"
 	testcases "github.com/soer3n/yaho/tests/testcases/helm"
 	"github.com/stretchr/testify/assert"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/repo"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
 
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
@@ -42,3 +60,324 @@ func TestRepoUpdate(t *testing.T) {
 		assert.Equal(err, apiObj.ReturnError["update"])
 	}
 }
+
+func TestRepoUpdateOCI(t *testing.T) {
+	registryMock := mocks.NewRegistryMock()
+	defer registryMock.Close()
+
+	assert := assert.New(t)
+
+	_ = helmv1alpha1.AddToScheme(scheme.Scheme)
+
+	err := registryMock.AddChart("charts", "../../../testutils/busybox-0.1.0.tgz")
+	assert.Nil(err)
+
+	r := testcases.GetTestRepoOCISpec(registryMock.Host())
+	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(r).Build()
+
+	testObj := repository.New(r, "default", context.TODO(), cli.New(), logf.Log, c, &http.Client{}, kube.Client{})
+	err = testObj.Update(r, scheme.Scheme)
+	assert.Nil(err)
+
+	ix, err := utils.LoadChartIndex("busybox", "oci", "default", c)
+	assert.Nil(err)
+	assert.Len(*ix, 1)
+	assert.Equal("0.1.0", (*ix)[0].Version)
+	assert.Equal("oci://"+registryMock.Host()+"/charts/busybox:0.1.0", (*ix)[0].URLs[0])
+}
+
+func TestRepoUpdateGit(t *testing.T) {
+	assert := assert.New(t)
+
+	_ = helmv1alpha1.AddToScheme(scheme.Scheme)
+
+	dir := t.TempDir()
+	c, err := loader.LoadFile("../../../testutils/busybox-0.1.0.tgz")
+	assert.Nil(err)
+	assert.Nil(chartutil.SaveDir(c, filepath.Join(dir, "charts")))
+
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		assert.Nil(err, string(out))
+	}
+
+	r := testcases.GetTestRepoGitSpec("file://" + dir)
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(r).Build()
+
+	testObj := repository.New(r, "default", context.TODO(), cli.New(), logf.Log, k8sClient, &http.Client{}, kube.Client{})
+	err = testObj.Update(r, scheme.Scheme)
+	assert.Nil(err)
+
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = dir
+	revision, err := cmd.Output()
+	assert.Nil(err)
+
+	ix, err := utils.LoadChartIndex("busybox", "git", "default", k8sClient)
+	assert.Nil(err)
+	assert.Len(*ix, 1)
+	assert.Equal("0.1.0", (*ix)[0].Version)
+	assert.Equal("charts/busybox", (*ix)[0].URLs[0])
+	assert.Empty((*ix)[0].Digest)
+	assert.Equal(strings.TrimSpace(string(revision)), (*ix)[0].Annotations[utils.GitRevisionAnnotation])
+
+	// the search path has to stay within the repository
+	r.Spec.Git.Path = "../.."
+	testObj = repository.New(r, "default", context.TODO(), cli.New(), logf.Log, k8sClient, &http.Client{}, kube.Client{})
+	assert.ErrorContains(testObj.Update(r, scheme.Scheme), "outside of git repository")
+}
+
+func TestRepoUpdateNotModified(t *testing.T) {
+	assert := assert.New(t)
+
+	_ = helmv1alpha1.AddToScheme(scheme.Scheme)
+
+	index := repo.NewIndexFile()
+	assert.Nil(index.MustAdd(&chart.Metadata{APIVersion: "v2", Name: "busybox", Version: "0.1.0"}, "busybox-0.1.0.tgz", "", "sha256:1234"))
+	raw, err := yaml.Marshal(index)
+	assert.Nil(err)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+
+		if req.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write(raw)
+	}))
+	defer server.Close()
+
+	r := testcases.GetTestRepoSyncSpec(server.URL)
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(r).Build()
+
+	testObj := repository.New(r, "default", context.TODO(), cli.New(), logf.Log, k8sClient, server.Client(), kube.Client{})
+	assert.False(testObj.NotModified)
+	assert.Equal(`"v1"`, testObj.ETag)
+	assert.Contains(testObj.Digest, "sha256:")
+	assert.Nil(testObj.Update(r, scheme.Scheme))
+
+	r.Status.ETag = testObj.ETag
+	r.Status.ObservedGeneration = r.ObjectMeta.Generation
+
+	testObj = repository.New(r, "default", context.TODO(), cli.New(), logf.Log, k8sClient, server.Client(), kube.Client{})
+	assert.True(testObj.NotModified)
+	assert.Nil(testObj.Update(r, scheme.Scheme))
+	assert.Equal(2, requests)
+
+	ix, err := utils.LoadChartIndex("busybox", "sync", "default", k8sClient)
+	assert.Nil(err)
+	assert.Len(*ix, 1)
+
+	// a deleted index configmap is restored by requesting the index without conditions
+	assert.Nil(k8sClient.Delete(context.TODO(), &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "helm-sync-busybox-index", Namespace: "default"}}))
+
+	testObj = repository.New(r, "default", context.TODO(), cli.New(), logf.Log, k8sClient, server.Client(), kube.Client{})
+	assert.False(testObj.NotModified)
+	assert.Nil(testObj.Update(r, scheme.Scheme))
+	assert.Equal(3, requests)
+
+	ix, err = utils.LoadChartIndex("busybox", "sync", "default", k8sClient)
+	assert.Nil(err)
+	assert.Len(*ix, 1)
+}
+
+func TestRepoUpdateChartSpec(t *testing.T) {
+	assert := assert.New(t)
+
+	_ = helmv1alpha1.AddToScheme(scheme.Scheme)
+
+	index := repo.NewIndexFile()
+	assert.Nil(index.MustAdd(&chart.Metadata{APIVersion: "v2", Name: "busybox", Version: "0.1.0"}, "busybox-0.1.0.tgz", "", "sha256:1234"))
+	raw, err := yaml.Marshal(index)
+	assert.Nil(err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write(raw)
+	}))
+	defer server.Close()
+
+	r := testcases.GetTestRepoSyncSpec(server.URL)
+	existing := &helmv1alpha1.Chart{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "busybox-sync",
+			Labels: map[string]string{
+				"yaho.soer3n.dev/repo":  "sync",
+				"yaho.soer3n.dev/chart": "busybox",
+			},
+		},
+		Spec: helmv1alpha1.ChartSpec{
+			Name:       "busybox",
+			Repository: "sync",
+			Suspend:    true,
+			Verify:     &helmv1alpha1.ChartVerification{KeyringSecret: "keyring"},
+		},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(r, existing).Build()
+
+	testObj := repository.New(r, "default", context.TODO(), cli.New(), logf.Log, k8sClient, server.Client(), kube.Client{})
+	assert.Nil(testObj.Update(r, scheme.Scheme))
+
+	// fields which are not owned by the repository are kept
+	c := &helmv1alpha1.Chart{}
+	assert.Nil(k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(existing), c))
+	assert.True(c.Spec.CreateDeps)
+	assert.True(c.Spec.Suspend)
+	assert.Equal(&helmv1alpha1.ChartVerification{KeyringSecret: "keyring"}, c.Spec.Verify)
+}
+
+func TestRepoUpdateFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	_ = helmv1alpha1.AddToScheme(scheme.Scheme)
+
+	index := repo.NewIndexFile()
+
+	for _, c := range []*chart.Metadata{
+		{APIVersion: "v2", Name: "testing", Version: "0.1.0"},
+		{APIVersion: "v2", Name: "testing", Version: "0.2.0"},
+		{APIVersion: "v2", Name: "testing-dep", Version: "0.1.0"},
+		{APIVersion: "v2", Name: "testing-nested", Version: "0.1.0"},
+		{APIVersion: "v2", Name: "busybox", Version: "0.1.0"},
+		{APIVersion: "v2", Name: "nginx", Version: "0.1.0"},
+	} {
+		assert.Nil(index.MustAdd(c, c.Name+"-"+c.Version+".tgz", "", "sha256:1234"))
+	}
+
+	raw, err := yaml.Marshal(index)
+	assert.Nil(err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write(raw)
+	}))
+	defer server.Close()
+
+	r := testcases.GetTestRepoFilterSpec(server.URL)
+	stale := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "helm-filter-nginx-index",
+			Namespace: "default",
+			Labels: map[string]string{
+				"yaho.soer3n.dev/repo":  "filter",
+				"yaho.soer3n.dev/chart": "nginx",
+				"yaho.soer3n.dev/type":  "index",
+			},
+		},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(r, stale).Build()
+
+	testObj := repository.New(r, "default", context.TODO(), cli.New(), logf.Log, k8sClient, server.Client(), kube.Client{})
+	assert.Nil(testObj.Update(r, scheme.Scheme))
+
+	ix, err := utils.LoadChartIndex("testing", "filter", "default", k8sClient)
+	assert.Nil(err)
+	assert.Len(*ix, 1)
+	assert.Equal("0.1.0", (*ix)[0].Version)
+
+	_, err = utils.LoadChartIndex("busybox", "filter", "default", k8sClient)
+	assert.Nil(err)
+
+	configmaps := &v1.ConfigMapList{}
+	assert.Nil(k8sClient.List(context.TODO(), configmaps))
+	assert.Len(configmaps.Items, 2)
+
+	assert.Equal(map[string]string{
+		"nginx":          "not included by filter",
+		"testing-nested": "excluded by filter testing-nested",
+		"testing-dep":    "no version matches constraint >=1.0.0",
+	}, testObj.SkippedCharts)
+}
+
+func TestRepoUpdateTLS(t *testing.T) {
+	assert := assert.New(t)
+
+	_ = helmv1alpha1.AddToScheme(scheme.Scheme)
+
+	index := repo.NewIndexFile()
+	assert.Nil(index.MustAdd(&chart.Metadata{APIVersion: "v2", Name: "busybox", Version: "0.1.0"}, "busybox-0.1.0.tgz", "", "sha256:1234"))
+	raw, err := yaml.Marshal(index)
+	assert.Nil(err)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write(raw)
+	}))
+	defer server.Close()
+
+	r := testcases.GetTestRepoTLSSpec(server.URL)
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "tls",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"ca.crt": pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}),
+		},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(r, secret).Build()
+
+	testObj := repository.New(r, "default", context.TODO(), cli.New(), logf.Log, k8sClient, &http.Client{}, kube.Client{})
+	assert.Nil(testObj.Update(r, scheme.Scheme))
+
+	ix, err := utils.LoadChartIndex("busybox", "tls", "default", k8sClient)
+	assert.Nil(err)
+	assert.Len(*ix, 1)
+
+	// server certificate is not trusted without the ca bundle
+	r.Spec.TLSSecret = ""
+	testObj = repository.New(r, "default", context.TODO(), cli.New(), logf.Log, k8sClient, &http.Client{}, kube.Client{})
+	assert.NotNil(testObj.Update(r, scheme.Scheme))
+
+	r.Spec.InsecureSkipTLSVerify = true
+	testObj = repository.New(r, "default", context.TODO(), cli.New(), logf.Log, k8sClient, &http.Client{}, kube.Client{})
+	assert.Nil(testObj.Update(r, scheme.Scheme))
+}
+
+func TestRepoUpdateToken(t *testing.T) {
+	assert := assert.New(t)
+
+	_ = helmv1alpha1.AddToScheme(scheme.Scheme)
+
+	index := repo.NewIndexFile()
+	assert.Nil(index.MustAdd(&chart.Metadata{APIVersion: "v2", Name: "busybox", Version: "0.1.0"}, "busybox-0.1.0.tgz", "", "sha256:1234"))
+	raw, err := yaml.Marshal(index)
+	assert.Nil(err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer secret" || req.Header.Get("X-Api-Key") != "foo" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		_, _ = w.Write(raw)
+	}))
+	defer server.Close()
+
+	r := testcases.GetTestRepoTokenSpec(server.URL)
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "token",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"token":   []byte(b64.StdEncoding.EncodeToString([]byte("secret"))),
+			"headers": []byte(b64.StdEncoding.EncodeToString([]byte("x-api-key: foo\n"))),
+		},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(r, secret).Build()
+
+	testObj := repository.New(r, "default", context.TODO(), cli.New(), logf.Log, k8sClient, &http.Client{}, kube.Client{})
+	assert.Nil(testObj.Update(r, scheme.Scheme))
+
+	ix, err := utils.LoadChartIndex("busybox", "token", "default", k8sClient)
+	assert.Nil(err)
+	assert.Len(*ix, 1)
+}
@@ -1,11 +1,28 @@
 package utils
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	helmv1alpha1 "github.com/soer3n/yaho/apis/yaho/v1alpha1"
 	"github.com/soer3n/yaho/internal/utils"
+	testcases "github.com/soer3n/yaho/tests/testcases/helm"
 	"github.com/stretchr/testify/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 func TestContains(t *testing.T) {
@@ -93,3 +110,273 @@ func TestConvertChartVersions(t *testing.T) {
 	assert.Len(cvs, 2)
 }
 */
+
+func TestSetRequestAuth(t *testing.T) {
+	assert := assert.New(t)
+
+	headers := utils.ParseAuthHeaders("x-api-key: foo\ninvalid\n X-Tenant : bar\n")
+	assert.Equal(map[string]string{"X-Api-Key": "foo", "X-Tenant": "bar"}, headers)
+
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/index.yaml", nil)
+	utils.SetRequestAuth(req, "", "", "token", headers)
+	assert.Equal("Bearer token", req.Header.Get("Authorization"))
+	assert.Equal("foo", req.Header.Get("X-Api-Key"))
+
+	// basic auth is preferred over a bearer token
+	req, _ = http.NewRequest(http.MethodGet, "https://example.com/index.yaml", nil)
+	utils.SetRequestAuth(req, "user", "password", "token", nil)
+	user, password, ok := req.BasicAuth()
+	assert.True(ok)
+	assert.Equal("user", user)
+	assert.Equal("password", password)
+}
+
+func TestIsSameHost(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(utils.IsSameHost("https://example.com/charts/foo-0.1.0.tgz", "https://example.com/charts"))
+	assert.False(utils.IsSameHost("https://cdn.example.com/foo-0.1.0.tgz", "https://example.com/charts"))
+	assert.False(utils.IsSameHost("http://example.com/foo-0.1.0.tgz", "https://example.com/charts"))
+	assert.False(utils.IsSameHost("https://example.com:8443/foo-0.1.0.tgz", "https://example.com/charts"))
+}
+
+func TestNewRegistryClient(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv("TMPDIR", t.TempDir())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "user" || password != "secret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"name":"charts/foo","tags":["0.1.0","0.2.0"]}`)
+	}))
+	defer server.Close()
+
+	repoURL := "oci://" + strings.TrimPrefix(server.URL, "http://") + "/charts"
+	ref := utils.GetRegistryRef(repoURL, "foo")
+
+	client, err := utils.NewRegistryClient(repoURL, "user", "secret", true, nil)
+	assert.Nil(err)
+
+	// credentials of another client for the same registry don't affect existing clients
+	other, err := utils.NewRegistryClient(repoURL, "user", "wrong", true, nil)
+	assert.Nil(err)
+
+	tags, err := client.Tags(ref)
+	assert.Nil(err)
+	assert.Equal([]string{"0.2.0", "0.1.0"}, tags)
+
+	_, err = other.Tags(ref)
+	assert.NotNil(err)
+
+	// no credentials are left on disk
+	files, err := os.ReadDir(filepath.Join(os.TempDir(), "yaho", "registry"))
+	assert.Nil(err)
+	assert.Empty(files)
+}
+
+func TestGitCheckout(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(utils.IsGitSSHURL("ssh://git@example.com/org/charts.git"))
+	assert.True(utils.IsGitSSHURL("git@example.com:org/charts.git"))
+	assert.False(utils.IsGitSSHURL("https://example.com/org/charts.git"))
+	assert.False(utils.IsGitSSHURL("file:///tmp/charts"))
+	assert.False(utils.IsGitSSHURL("/tmp/charts:old"))
+
+	// ssh urls require known hosts
+	source := &helmv1alpha1.GitSource{Branch: "main"}
+	err := utils.WithGitCheckout("ssh-test", "git@example.com:org/charts.git", source, &utils.GitAuth{SSHKey: "key"}, func(dir, revision string) error {
+		return nil
+	})
+	assert.NotNil(err)
+
+	if err != nil {
+		assert.Contains(err.Error(), "knownHosts")
+	}
+
+	dir := t.TempDir()
+	revisions := []string{}
+
+	for _, content := range []string{"first", "second"} {
+		assert.Nil(os.WriteFile(filepath.Join(dir, "file"), []byte(content), 0o600))
+
+		for _, args := range [][]string{
+			{"init", "-q", "-b", "main"},
+			{"add", "."},
+			{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", content},
+		} {
+			cmd := exec.Command("git", args...)
+			cmd.Dir = dir
+			out, err := cmd.CombinedOutput()
+			assert.Nil(err, string(out))
+		}
+
+		out, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+		assert.Nil(err)
+		revisions = append(revisions, strings.TrimSpace(string(out)))
+	}
+
+	// a recorded commit is checked out instead of the head of the branch
+	source.Commit = revisions[0]
+	err = utils.WithGitCheckout("pin-test", "file://"+dir, source, nil, func(checkout, revision string) error {
+		assert.Equal(revisions[0], revision)
+
+		content, err := os.ReadFile(filepath.Join(checkout, "file"))
+		assert.Nil(err)
+		assert.Equal("first", string(content))
+		return nil
+	})
+	assert.Nil(err)
+
+	// paths have to stay within the working copy
+	path, err := utils.JoinGitPath(dir, "charts/../charts/foo")
+	assert.Nil(err)
+	assert.Equal(filepath.Join(dir, "charts", "foo"), path)
+
+	_, err = utils.JoinGitPath(dir, "../../etc")
+	assert.NotNil(err)
+}
+
+func TestParseKubeconfig(t *testing.T) {
+	assert := assert.New(t)
+
+	config, err := utils.ParseKubeconfig(testcases.GetTestKubeconfig(), "")
+	assert.Nil(err)
+	assert.Equal("https://127.0.0.1:6443", utils.GetKubeconfigServer(config))
+
+	// the kubeconfig is reduced to the selected context
+	config, err = utils.ParseKubeconfig(testcases.GetTestKubeconfig(), "remote")
+	assert.Nil(err)
+	assert.Len(config.Contexts, 1)
+	assert.Len(config.Clusters, 1)
+	assert.Len(config.AuthInfos, 1)
+	assert.Equal("https://remote.example:6443", utils.GetKubeconfigServer(config))
+
+	_, err = utils.ParseKubeconfig(testcases.GetTestKubeconfig(), "missing")
+	assert.NotNil(err)
+
+	_, err = utils.ParseKubeconfig([]byte("invalid"), "")
+	assert.NotNil(err)
+}
+
+func TestParseKubeconfigCredentials(t *testing.T) {
+	assert := assert.New(t)
+
+	for name, raw := range testcases.GetTestUnsafeKubeconfigs() {
+		_, err := utils.ParseKubeconfig(raw, "")
+		assert.NotNil(err, name)
+
+		if err != nil {
+			assert.Contains(err.Error(), "supported", name)
+		}
+	}
+
+	// local kubeconfigs have their files embedded before they are validated
+	config, err := clientcmd.Load(testcases.GetTestUnsafeKubeconfigs()["token file"])
+	assert.Nil(err)
+	assert.Nil(utils.PrepareKubeconfig(config, ""))
+	assert.Empty(config.AuthInfos["remote-admin"].TokenFile)
+
+	config, err = clientcmd.Load(testcases.GetTestUnsafeKubeconfigs()["exec"])
+	assert.Nil(err)
+	assert.NotNil(utils.PrepareKubeconfig(config, ""))
+}
+
+func TestGetClusterKubeconfig(t *testing.T) {
+	assert := assert.New(t)
+	cluster := testcases.GetTestClusterReference()
+
+	c := fake.NewClientBuilder().WithObjects(testcases.GetTestKubeconfigSecret("config")).Build()
+
+	config, err := utils.GetClusterKubeconfig(c, "foo", "bar", cluster)
+	assert.Nil(err)
+	assert.Equal("bar", config.Contexts[config.CurrentContext].Namespace)
+	assert.Equal("remote-token", config.AuthInfos["remote-admin"].Token)
+
+	// the default key is used without a key in the reference and missing in the secret
+	cluster.Key = ""
+	_, err = utils.GetClusterKubeconfig(c, "foo", "bar", cluster)
+	assert.NotNil(err)
+
+	secret, err := utils.NewKubeconfigSecret("remote-cluster", "foo", config)
+	assert.Nil(err)
+	assert.Contains(string(secret.Data[utils.KubeconfigSecretKey]), "remote.example")
+}
+
+func TestGetServiceAccountToken(t *testing.T) {
+	assert := assert.New(t)
+	requests := 0
+
+	clientset := k8sfake.NewSimpleClientset()
+	clientset.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		requests++
+		return true, &authenticationv1.TokenRequest{
+			Status: authenticationv1.TokenRequestStatus{
+				Token:               fmt.Sprintf("token-%v", requests),
+				ExpirationTimestamp: metav1.NewTime(time.Now().Add(time.Hour)),
+			},
+		}, nil
+	})
+
+	token, err := utils.GetServiceAccountToken(clientset, "foo", "token-test")
+	assert.Nil(err)
+	assert.Equal("token-1", token)
+
+	// the cached token is used as long as it is valid long enough
+	token, err = utils.GetServiceAccountToken(clientset, "foo", "token-test")
+	assert.Nil(err)
+	assert.Equal("token-1", token)
+	assert.Equal(1, requests)
+
+	token, err = utils.GetServiceAccountToken(clientset, "bar", "token-test")
+	assert.Nil(err)
+	assert.Equal("token-2", token)
+}
+
+func TestNewImpersonationKubeconfig(t *testing.T) {
+	assert := assert.New(t)
+
+	rc := &rest.Config{
+		Host:        "https://127.0.0.1:6443",
+		BearerToken: "agent-token",
+		TLSClientConfig: rest.TLSClientConfig{
+			CAData: []byte("ca"),
+		},
+	}
+
+	config, err := utils.NewImpersonationKubeconfig(rc, "foo", "releases", "bar")
+	assert.Nil(err)
+
+	kubeContext := config.Contexts[config.CurrentContext]
+	assert.Equal("bar", kubeContext.Namespace)
+	assert.Equal("system:serviceaccount:foo:releases", config.AuthInfos[kubeContext.AuthInfo].Impersonate)
+	assert.Equal("agent-token", config.AuthInfos[kubeContext.AuthInfo].Token)
+	assert.Equal("https://127.0.0.1:6443", utils.GetKubeconfigServer(config))
+
+	// the token file of the agent is used instead of the token if present
+	rc.BearerTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	config, err = utils.NewImpersonationKubeconfig(rc, "foo", "releases", "bar")
+	assert.Nil(err)
+	assert.Empty(config.AuthInfos[kubeContext.AuthInfo].Token)
+	assert.Equal(rc.BearerTokenFile, config.AuthInfos[kubeContext.AuthInfo].TokenFile)
+}
+
+func TestSetSuspendedCondition(t *testing.T) {
+	assert := assert.New(t)
+	conditions := []metav1.Condition{{Type: "synced", Status: metav1.ConditionTrue}}
+
+	assert.True(utils.SetSuspendedCondition(&conditions, true, "release is suspended"))
+	assert.Len(conditions, 2)
+	assert.Equal("release is suspended", conditions[1].Message)
+	assert.False(utils.SetSuspendedCondition(&conditions, true, "release is suspended"))
+
+	// the condition is removed after resuming
+	assert.True(utils.SetSuspendedCondition(&conditions, false, ""))
+	assert.Equal([]metav1.Condition{{Type: "synced", Status: metav1.ConditionTrue}}, conditions)
+	assert.False(utils.SetSuspendedCondition(&conditions, false, ""))
+}